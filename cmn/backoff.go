@@ -0,0 +1,64 @@
+package cmn
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Backoff produces a sequence of retry delays: Base, Base*Factor,
+// Base*Factor^2... capped at Max ( when non-zero ), each randomized by up to
+// ±Jitter of its value ( see Jitter() ) without exceeding Max. A Factor of 1
+// or less yields a constant delay. Safe for concurrent use, but note that
+// concurrent callers advance a single shared sequence.
+type Backoff struct {
+	Base   time.Duration
+	Factor float64
+	Max    time.Duration
+	Jitter float64
+
+	mu      sync.Mutex
+	attempt int
+}
+
+// ExponentialBackoff returns a Backoff doubling from base up to maxDelay, with a
+// ±jitter fraction applied to every delay.
+func ExponentialBackoff(base, maxDelay time.Duration, jitter float64) *Backoff {
+	return &Backoff{Base: base, Factor: 2, Max: maxDelay, Jitter: jitter}
+}
+
+// ConstantBackoff returns a Backoff always yielding d
+func ConstantBackoff(d time.Duration) *Backoff {
+	return &Backoff{Base: d, Factor: 1}
+}
+
+// Next returns the delay before the upcoming attempt and advances the sequence
+func (b *Backoff) Next() time.Duration {
+	b.mu.Lock()
+	n := b.attempt
+	b.attempt++
+	b.mu.Unlock()
+
+	d := float64(b.Base)
+	if b.Factor > 1 {
+		d *= math.Pow(b.Factor, float64(n))
+	}
+	if b.Max > 0 && d > float64(b.Max) {
+		d = float64(b.Max)
+	} else if d > math.MaxInt64 {
+		d = math.MaxInt64
+	}
+
+	ret := Jitter(time.Duration(d), b.Jitter)
+	if b.Max > 0 && ret > b.Max {
+		ret = b.Max
+	}
+	return ret
+}
+
+// Reset restarts the sequence from Base, typically after a success
+func (b *Backoff) Reset() {
+	b.mu.Lock()
+	b.attempt = 0
+	b.mu.Unlock()
+}
@@ -0,0 +1,84 @@
+package cmn
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJitterBounds(t *testing.T) {
+	const d = time.Second
+	for _, frac := range []float64{0.1, 0.5, 1, 3} {
+		lo, hi := d, d
+		bound := frac
+		if bound > 1 {
+			bound = 1 // clamped
+		}
+		for i := 0; i < 10000; i++ {
+			j := Jitter(d, frac)
+			if j < time.Duration(float64(d)*(1-bound)) || j > time.Duration(float64(d)*(1+bound)) {
+				t.Fatalf("Jitter(%s, %v) returned %s, outside of ±%v", d, frac, j, bound)
+			}
+			lo, hi = min(lo, j), max(hi, j)
+		}
+		// the spread actually covers most of the range
+		if spread := float64(hi-lo) / float64(d); spread < bound {
+			t.Errorf("Jitter(%s, %v) spread of %v suspiciously narrow", d, frac, spread)
+		}
+	}
+
+	for _, frac := range []float64{0, -1} {
+		if j := Jitter(d, frac); j != d {
+			t.Errorf("Jitter(%s, %v) altered the duration: %s", d, frac, j)
+		}
+	}
+	if j := Jitter(-d, 0.5); j != -d {
+		t.Errorf("Jitter of a negative duration altered it: %s", j)
+	}
+}
+
+func TestExponentialBackoffCap(t *testing.T) {
+	b := ExponentialBackoff(100*time.Millisecond, time.Second, 0)
+	expected := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	}
+	for i, exp := range expected {
+		if d := b.Next(); d != exp {
+			t.Fatalf("attempt %d: got %s, expected %s", i, d, exp)
+		}
+	}
+	// no overflow far down the sequence
+	for i := 0; i < 200; i++ {
+		if d := b.Next(); d != time.Second {
+			t.Fatalf("delay %s past the cap", d)
+		}
+	}
+
+	b.Reset()
+	if d := b.Next(); d != 100*time.Millisecond {
+		t.Errorf("first delay after Reset is %s", d)
+	}
+}
+
+func TestExponentialBackoffJitterCap(t *testing.T) {
+	const base, maxDelay = 100 * time.Millisecond, time.Second
+	b := ExponentialBackoff(base, maxDelay, 0.5)
+	for i := 0; i < 1000; i++ {
+		if d := b.Next(); d > maxDelay || d < base/2 {
+			t.Fatalf("attempt %d: jittered delay %s outside of [%s,%s]", i, d, base/2, maxDelay)
+		}
+	}
+}
+
+func TestConstantBackoff(t *testing.T) {
+	b := ConstantBackoff(time.Second)
+	for i := 0; i < 10; i++ {
+		if d := b.Next(); d != time.Second {
+			t.Fatalf("attempt %d: got %s", i, d)
+		}
+	}
+}
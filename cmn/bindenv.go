@@ -0,0 +1,116 @@
+package cmn
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// BindEnv populates the fields of the struct pointed to by v from environment
+// variables named by their `env:"NAME"` tags, prefixed by prefix. A tag of
+// `env:"NAME,required"` makes an unset ( or empty ) variable an error, while
+// otherwise the field retains its existing value. Untagged struct fields are
+// descended into. Supported field types are strings, all int/uint/float
+// kinds, bool ( as per ParseBool ), time.Duration and []string ( as per
+// ParseList ). All problems are reported at once, as an Errors.
+func BindEnv(v interface{}, prefix string) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return WrErr(fmt.Errorf("BindEnv requires a non-nil pointer to a struct, got %T", v))
+	}
+
+	var errs Errors
+	bindEnvInto(rv.Elem(), prefix, &errs)
+	return errs.ErrOrNil()
+}
+
+func bindEnvInto(rv reflect.Value, prefix string, errs *Errors) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		fv := rv.Field(i)
+
+		tag, hasTag := sf.Tag.Lookup("env")
+		if !hasTag {
+			if fv.Kind() == reflect.Struct && fv.Type() != durationType {
+				bindEnvInto(fv, prefix, errs)
+			}
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		name = prefix + name
+
+		val := os.Getenv(name)
+		if val == "" {
+			if opts == "required" {
+				*errs = append(*errs, WrErr(fmt.Errorf("required environment variable %s ( field %s ) is not set", name, sf.Name)))
+			}
+			continue
+		}
+
+		if err := setFromString(fv, val); err != nil {
+			*errs = append(*errs, WrErr(fmt.Errorf("environment variable %s ( field %s ): %w", name, sf.Name, err)))
+		}
+	}
+}
+
+func setFromString(fv reflect.Value, s string) error {
+	if fv.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(s)
+	case reflect.Bool:
+		b, err := ParseBool(s)
+		if err != nil {
+			return errors.Unwrap(err) // do not double-frame
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 0, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 0, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported field type %s", fv.Type())
+		}
+		fv.Set(reflect.ValueOf(ParseList(s)).Convert(fv.Type()))
+	default:
+		return fmt.Errorf("unsupported field type %s", fv.Type())
+	}
+	return nil
+}
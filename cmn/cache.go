@@ -0,0 +1,114 @@
+package cmn
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Cache is an in-memory key/value cache with per-entry expiration, which
+// collapses concurrent loads of the same missing key into a single call. The
+// zero value is not usable, see NewCache. Expired entries are only evicted
+// upon access or via Prune().
+type Cache[K comparable, V any] struct {
+	ttl      time.Duration
+	ErrorTTL time.Duration // how long a failed load is remembered, by default ( 0 ) errors are not cached
+
+	mu      sync.Mutex
+	entries map[K]*cacheEntry[V]
+}
+
+type cacheEntry[V any] struct {
+	loaded  chan struct{}
+	val     V
+	err     error
+	expires time.Time
+}
+
+// NewCache returns an empty Cache retaining values for ttl
+func NewCache[K comparable, V any](ttl time.Duration) *Cache[K, V] {
+	return &Cache[K, V]{ttl: ttl, entries: make(map[K]*cacheEntry[V])}
+}
+
+// GetOrLoad returns the unexpired cached value for k, or invokes loader to
+// obtain it. Callers arriving while a load of k is in flight wait for its
+// result ( or for their own ctx to be done ) instead of loading again. Note
+// that the loader receives the ctx of the caller which triggered it: its
+// cancellation fails the load for all waiters. A panic in loader is returned
+// as an error.
+func (c *Cache[K, V]) GetOrLoad(ctx context.Context, k K, loader func(ctx context.Context) (V, error)) (V, error) {
+	c.mu.Lock()
+	if e, found := c.entries[k]; found {
+		select {
+		case <-e.loaded:
+			if time.Now().Before(e.expires) {
+				c.mu.Unlock()
+				return e.val, e.err
+			}
+			delete(c.entries, k)
+		default:
+			c.mu.Unlock()
+			select {
+			case <-e.loaded:
+				return e.val, e.err
+			case <-ctx.Done():
+				var zero V
+				return zero, WrErr(ctx.Err())
+			}
+		}
+	}
+	e := &cacheEntry[V]{loaded: make(chan struct{})}
+	c.entries[k] = e
+	c.mu.Unlock()
+
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				e.err = RecoverToErr(r)
+			}
+		}()
+		e.val, e.err = loader(ctx)
+	}()
+
+	c.mu.Lock()
+	if e.err == nil {
+		e.expires = time.Now().Add(c.ttl)
+	} else if c.ErrorTTL > 0 {
+		e.expires = time.Now().Add(c.ErrorTTL)
+	} else if c.entries[k] == e {
+		delete(c.entries, k)
+	}
+	close(e.loaded)
+	c.mu.Unlock()
+
+	return e.val, e.err
+}
+
+// Delete evicts k, without affecting a load already in flight
+func (c *Cache[K, V]) Delete(k K) {
+	c.mu.Lock()
+	if e, found := c.entries[k]; found {
+		select {
+		case <-e.loaded:
+			delete(c.entries, k)
+		default:
+		}
+	}
+	c.mu.Unlock()
+}
+
+// Prune evicts all expired entries
+func (c *Cache[K, V]) Prune() {
+	now := time.Now()
+	c.mu.Lock()
+	for k, e := range c.entries {
+		select {
+		case <-e.loaded:
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		default:
+		}
+	}
+	c.mu.Unlock()
+}
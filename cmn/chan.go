@@ -0,0 +1,61 @@
+package cmn
+
+import (
+	"context"
+	"sync"
+)
+
+// FanIn merges the given channels into a single one, which is closed once all
+// inputs are closed, or ctx is done. Upon ctx cancellation the forwarding
+// goroutines exit promptly without draining their inputs: use Drain on those
+// if their producers may otherwise block forever.
+func FanIn[T any](ctx context.Context, chans ...<-chan T) <-chan T {
+	out := make(chan T)
+
+	var wg sync.WaitGroup
+	wg.Add(len(chans))
+	for _, ch := range chans {
+		go func(ch <-chan T) {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case v, open := <-ch:
+					if !open {
+						return
+					}
+					select {
+					case out <- v:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}(ch)
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	return out
+}
+
+// Drain discards values from ch until it is closed or ctx is done, unblocking
+// any producers. Returns the number of values discarded.
+func Drain[T any](ctx context.Context, ch <-chan T) int {
+	var n int
+	for {
+		select {
+		case <-ctx.Done():
+			return n
+		case _, open := <-ch:
+			if !open {
+				return n
+			}
+			n++
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package cmn
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func produce(n, offset int) <-chan int {
+	ch := make(chan int)
+	go func() {
+		defer close(ch)
+		for i := 0; i < n; i++ {
+			ch <- offset + i
+		}
+	}()
+	return ch
+}
+
+func TestFanIn(t *testing.T) {
+	const producers, perProducer = 8, 100
+
+	chans := make([]<-chan int, producers)
+	for i := range chans {
+		chans[i] = produce(perProducer, i*perProducer)
+	}
+
+	var got []int
+	for v := range FanIn(context.Background(), chans...) {
+		got = append(got, v)
+	}
+
+	if len(got) != producers*perProducer {
+		t.Fatalf("received %d values, expected %d", len(got), producers*perProducer)
+	}
+	sort.Ints(got)
+	for i, v := range got {
+		if v != i {
+			t.Fatalf("value %d missing or duplicated", i)
+		}
+	}
+}
+
+func TestFanInNoInputs(t *testing.T) {
+	select {
+	case _, open := <-FanIn[int](context.Background()):
+		if open {
+			t.Fatal("unexpected value")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("output of no inputs not closed")
+	}
+}
+
+func TestFanInCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// producers never closing their channels
+	var wg sync.WaitGroup
+	chans := make([]<-chan int, 4)
+	for i := range chans {
+		ch := make(chan int)
+		chans[i] = ch
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case ch <- 1:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	out := FanIn(ctx, chans...)
+	for i := 0; i < 10; i++ {
+		<-out
+	}
+	cancel()
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		Drain(context.Background(), out)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("output not closed after ctx cancellation")
+	}
+	wg.Wait()
+}
+
+func TestDrain(t *testing.T) {
+	if n := Drain(context.Background(), produce(50, 0)); n != 50 {
+		t.Errorf("drained %d values, expected 50", n)
+	}
+
+	// a producer that never closes: Drain returns upon ctx cancellation
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan int)
+	go func() {
+		for i := 0; i < 5; i++ {
+			ch <- i
+		}
+		cancel()
+	}()
+	if n := Drain(ctx, ch); n != 5 {
+		t.Errorf("drained %d values, expected 5", n)
+	}
+}
@@ -0,0 +1,108 @@
+package cmn
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// CSVReader yields the rows of a CSV stream keyed by the names in its header
+// line. A leading UTF-8 BOM is ignored.
+type CSVReader struct {
+	r      *csv.Reader
+	header []string
+}
+
+// NewCSVReader reads the header line of r and returns a reader for the rest.
+// Empty or duplicate column names in the header are an error.
+func NewCSVReader(r io.Reader) (*CSVReader, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1 // we produce our own, more informative, errors
+
+	hdr, err := cr.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, WrErr(errors.New("csv input has no header line"))
+		}
+		return nil, WrErr(err)
+	}
+	hdr[0] = strings.TrimPrefix(hdr[0], utf8BOM)
+
+	seen := make(map[string]struct{}, len(hdr))
+	for i, h := range hdr {
+		if h == "" {
+			return nil, WrErr(fmt.Errorf("csv header column %d is empty", i+1))
+		}
+		if _, dup := seen[h]; dup {
+			return nil, WrErr(fmt.Errorf("csv header column '%s' is duplicated", h))
+		}
+		seen[h] = struct{}{}
+	}
+
+	return &CSVReader{r: cr, header: hdr}, nil
+}
+
+// Header returns the column names, in file order
+func (cr *CSVReader) Header() []string { return cr.header }
+
+// Read returns the next row, or io.EOF ( unwrapped ) when the input is
+// exhausted. A row with a column count differing from the header is an error.
+func (cr *CSVReader) Read() (map[string]string, error) {
+	rec, err := cr.r.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		return nil, WrErr(err)
+	}
+	if len(rec) != len(cr.header) {
+		line, _ := cr.r.FieldPos(0)
+		return nil, WrErr(fmt.Errorf("csv line %d has %d columns, header has %d", line, len(rec), len(cr.header)))
+	}
+
+	row := make(map[string]string, len(rec))
+	for i, v := range rec {
+		row[cr.header[i]] = v
+	}
+	return row, nil
+}
+
+// CSVWriter writes rows as CSV with a fixed, ordered set of columns
+type CSVWriter struct {
+	w       *csv.Writer
+	columns []string
+}
+
+// NewCSVWriter writes the header line of columns to w and returns a writer
+// for the subsequent rows. Call Flush() when done.
+func NewCSVWriter(w io.Writer, columns []string) (*CSVWriter, error) {
+	cw := &CSVWriter{w: csv.NewWriter(w), columns: columns}
+	if err := cw.w.Write(columns); err != nil {
+		return nil, WrErr(err)
+	}
+	return cw, nil
+}
+
+// Write emits a row: missing columns are written empty, while keys not among
+// the writer's columns are an error, as silently dropping data rarely is intended.
+func (cw *CSVWriter) Write(row map[string]string) error {
+	rec := make([]string, len(cw.columns))
+	for i, c := range cw.columns {
+		rec[i] = row[c]
+	}
+	for _, k := range SortedMapKeys(row) {
+		if !slices.Contains(cw.columns, k) {
+			return WrErr(fmt.Errorf("csv row contains unknown column '%s'", k))
+		}
+	}
+	return WrErr(cw.w.Write(rec))
+}
+
+// Flush writes any buffered data to the underlying writer
+func (cw *CSVWriter) Flush() error {
+	cw.w.Flush()
+	return WrErr(cw.w.Error())
+}
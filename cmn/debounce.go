@@ -0,0 +1,97 @@
+package cmn
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Debounce returns a trigger which, when called, (re)starts a timer of d:
+// fn is invoked once d has elapsed since the most recent trigger ( trailing
+// edge only ), thus a continuous burst of triggers results in a single call
+// after it subsides. fn runs in its own goroutine, never concurrently with
+// itself. Once ctx is done a pending call is abandoned and further triggers
+// are ignored. The trigger is safe for concurrent use.
+func Debounce(ctx context.Context, d time.Duration, fn func()) (trigger func()) {
+	var mu, runMu sync.Mutex
+	var t *time.Timer
+
+	run := func() {
+		if ctx.Err() != nil {
+			return
+		}
+		runMu.Lock()
+		defer runMu.Unlock()
+		fn()
+	}
+
+	context.AfterFunc(ctx, func() { //nolint:errcheck
+		mu.Lock()
+		if t != nil {
+			t.Stop()
+		}
+		mu.Unlock()
+	})
+
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if ctx.Err() != nil {
+			return
+		}
+		if t != nil {
+			t.Stop()
+		}
+		t = time.AfterFunc(d, run)
+	}
+}
+
+// Throttle returns a trigger invoking fn at most once per d: the first
+// trigger invokes fn immediately ( leading edge ), and any number of triggers
+// arriving within the following d are coalesced into a single call at its
+// end ( trailing edge ), which in turn opens a new window. fn runs in its own
+// goroutine, never concurrently with itself. Once ctx is done pending calls
+// are abandoned and further triggers are ignored. The trigger is safe for
+// concurrent use.
+func Throttle(ctx context.Context, d time.Duration, fn func()) (trigger func()) {
+	var mu, runMu sync.Mutex
+	var window *time.Timer
+	var pending bool
+
+	run := func() {
+		if ctx.Err() != nil {
+			return
+		}
+		runMu.Lock()
+		defer runMu.Unlock()
+		fn()
+	}
+
+	var windowEnd func()
+	windowEnd = func() {
+		mu.Lock()
+		if !pending || ctx.Err() != nil {
+			window = nil
+			mu.Unlock()
+			return
+		}
+		pending = false
+		window = time.AfterFunc(d, windowEnd)
+		mu.Unlock()
+		run()
+	}
+
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if ctx.Err() != nil {
+			return
+		}
+		if window != nil {
+			pending = true
+			return
+		}
+		window = time.AfterFunc(d, windowEnd)
+		go run()
+	}
+}
@@ -0,0 +1,97 @@
+package cmn
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const debounceTestWindow = 50 * time.Millisecond
+
+// triggers from many goroutines at once
+func burst(trigger func()) {
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				trigger()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestDebounce(t *testing.T) {
+	var calls atomic.Int32
+	trigger := Debounce(context.Background(), debounceTestWindow, func() { calls.Add(1) })
+
+	burst(trigger)
+	if n := calls.Load(); n != 0 {
+		t.Fatalf("debounced fn called %d times before the burst subsided", n)
+	}
+	time.Sleep(3 * debounceTestWindow)
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("debounced fn called %d times after a burst, expected 1", n)
+	}
+
+	burst(trigger)
+	time.Sleep(3 * debounceTestWindow)
+	if n := calls.Load(); n != 2 {
+		t.Fatalf("debounced fn called %d times after a second burst, expected 2", n)
+	}
+}
+
+func TestDebounceCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls atomic.Int32
+	trigger := Debounce(ctx, debounceTestWindow, func() { calls.Add(1) })
+
+	burst(trigger)
+	cancel()
+	burst(trigger)
+	time.Sleep(3 * debounceTestWindow)
+	if n := calls.Load(); n != 0 {
+		t.Fatalf("debounced fn called %d times after ctx cancellation", n)
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	var calls atomic.Int32
+	trigger := Throttle(context.Background(), debounceTestWindow, func() { calls.Add(1) })
+
+	burst(trigger)
+	time.Sleep(debounceTestWindow / 5)
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("throttled fn called %d times on the leading edge, expected 1", n)
+	}
+	time.Sleep(3 * debounceTestWindow)
+	if n := calls.Load(); n != 2 {
+		t.Fatalf("throttled fn called %d times after the window, expected 2 ( leading + trailing )", n)
+	}
+
+	// a lone trigger once everything quiesced is a leading edge again
+	trigger()
+	time.Sleep(3 * debounceTestWindow)
+	if n := calls.Load(); n != 3 {
+		t.Fatalf("throttled fn called %d times after a lone trigger, expected 3", n)
+	}
+}
+
+func TestThrottleCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls atomic.Int32
+	trigger := Throttle(ctx, debounceTestWindow, func() { calls.Add(1) })
+
+	burst(trigger)
+	time.Sleep(debounceTestWindow / 5)
+	cancel()
+	burst(trigger)
+	time.Sleep(3 * debounceTestWindow)
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("throttled fn called %d times, expected only the leading call before cancellation", n)
+	}
+}
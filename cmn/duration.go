@@ -0,0 +1,86 @@
+package cmn
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HumanDuration renders a duration in a compact and stable form. Durations of
+// a minute or more are rounded to whole seconds with zero trailing components
+// omitted ( `1h2m3s`, `2h`, `5m30s` ), shorter ones are expressed in the
+// largest unit fitting the rounded value, with at most one decimal place
+// below 10 of that unit ( `450ms`, `2.3µs`, `12s`, and `1s` for 999.6ms ).
+func HumanDuration(d time.Duration) string {
+	if d == 0 {
+		return "0s"
+	} else if d == math.MinInt64 {
+		// not negatable, and 1ns is lost in the rounding to seconds anyway
+		d++
+	}
+	if d < 0 {
+		return "-" + HumanDuration(-d)
+	}
+
+	if d >= time.Minute {
+		// in whole seconds rather than via Duration.Round(), which saturates near the top of the range
+		secs := int64(d / time.Second)
+		if d%time.Second >= time.Second/2 {
+			secs++
+		}
+		h, m, sec := secs/3600, secs/60%60, secs%60
+
+		var b strings.Builder
+		if h > 0 {
+			b.WriteString(strconv.FormatInt(h, 10) + "h")
+		}
+		if m > 0 || (h > 0 && sec > 0) {
+			b.WriteString(strconv.FormatInt(m, 10) + "m")
+		}
+		if sec > 0 {
+			b.WriteString(strconv.FormatInt(sec, 10) + "s")
+		}
+		return b.String()
+	}
+
+	for _, u := range []struct {
+		unit time.Duration
+		sfx  string
+	}{
+		{time.Second, "s"},
+		{time.Millisecond, "ms"},
+		{time.Microsecond, "µs"},
+	} {
+		if d < u.unit {
+			continue
+		}
+		prec := u.unit
+		if d < 10*u.unit {
+			prec /= 10
+		}
+		// round before settling on a unit: 999.6ms is `1s`, not `1000ms`
+		if r := d.Round(prec); r != d {
+			return HumanDuration(r)
+		}
+		return strconv.FormatFloat(float64(d)/float64(u.unit), 'f', -1, 64) + u.sfx
+	}
+
+	return strconv.FormatInt(int64(d), 10) + "ns"
+}
+
+// Jitter returns d randomly adjusted by up to ±frac*d ( uniformly distributed ),
+// never returning a negative duration. A frac outside of (0,1] is clamped.
+func Jitter(d time.Duration, frac float64) time.Duration {
+	if frac <= 0 || d <= 0 {
+		return d
+	} else if frac > 1 {
+		frac = 1
+	}
+	j := time.Duration((rand.Float64()*2 - 1) * frac * float64(d)) //nolint:gosec
+	if d+j < 0 {
+		return 0
+	}
+	return d + j
+}
@@ -0,0 +1,46 @@
+package cmn
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestHumanDuration(t *testing.T) {
+	for _, tc := range []struct {
+		d   time.Duration
+		out string
+	}{
+		{0, "0s"},
+		{999, "999ns"},
+		{2300, "2.3µs"},
+		{450 * time.Millisecond, "450ms"},
+		{1234 * time.Millisecond, "1.2s"},
+		{12 * time.Second, "12s"},
+		{-1500 * time.Millisecond, "-1.5s"},
+		{time.Hour + 2*time.Minute + 3*time.Second + 456*time.Millisecond, "1h2m3s"},
+		{2 * time.Hour, "2h"},
+		{time.Hour + 2*time.Minute, "1h2m"},
+		{5*time.Minute + 30*time.Second, "5m30s"},
+		// rounding carries over into the next unit
+		{59960 * time.Millisecond, "1m"},
+		{59499 * time.Millisecond, "59s"},
+		{9960 * time.Millisecond, "10s"},
+		{9940 * time.Millisecond, "9.9s"},
+		{999600 * time.Microsecond, "1s"},
+		{999600 * time.Nanosecond, "1ms"},
+		{9999 * time.Nanosecond, "10µs"},
+		{time.Hour - 400*time.Millisecond, "1h"},
+		{time.Minute - time.Nanosecond, "1m"},
+		{time.Hour + 3*time.Second, "1h0m3s"},
+		// the extremes
+		{math.MaxInt64, "2562047h47m17s"},
+		{math.MinInt64, "-2562047h47m17s"},
+		{math.MinInt64 + 1, "-2562047h47m17s"},
+		{-time.Nanosecond, "-1ns"},
+	} {
+		if got := HumanDuration(tc.d); got != tc.out {
+			t.Errorf("HumanDuration(%s) = %q, expected %q", tc.d, got, tc.out)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package cmn
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// IsSecretKey is the default heuristic deciding whether a key names a secret:
+// it matches ( case-insensitively ) names ending in _PASS, _PASSWORD, _TOKEN
+// or _SECRET, as well as those exact names.
+func IsSecretKey(key string) bool {
+	k := strings.ToUpper(key)
+	for _, s := range []string{"PASS", "PASSWORD", "TOKEN", "SECRET"} {
+		if k == s || strings.HasSuffix(k, "_"+s) {
+			return true
+		}
+	}
+	return false
+}
+
+// EnvMap returns a snapshot of the environment variables whose names start
+// with prefix ( all of them for an empty prefix ), with the values of
+// IsSecretKey-matching names replaced by RedactedValue
+func EnvMap(prefix string) map[string]string {
+	return EnvMapWith(prefix, IsSecretKey)
+}
+
+// EnvMapWith is EnvMap with a custom secret-name matcher. A nil matcher
+// disables redaction.
+func EnvMapWith(prefix string, isSecret func(key string) bool) map[string]string {
+	ret := make(map[string]string)
+	for _, kv := range os.Environ() {
+		k, v, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		if isSecret != nil && isSecret(k) {
+			v = RedactedValue
+		}
+		ret[k] = v
+	}
+	return ret
+}
+
+// ReadEnvFile parses a dotenv-style file into a map. Supported syntax:
+// blank lines and lines starting with # are skipped, an optional `export `
+// prefix is ignored, and each entry is KEY=VALUE where VALUE may be
+// unquoted ( surrounding whitespace and a trailing ` #comment` are removed ),
+// 'single-quoted' ( taken literally ) or "double-quoted" ( honoring \n, \t,
+// \", \\ escapes ). A leading UTF-8 BOM is ignored.
+func ReadEnvFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, WrErr(err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	ret := make(map[string]string)
+	first := true
+	if err := ScanLines(f, 0, func(l []byte) error {
+		if first {
+			l, first = StripBOM(l), false
+		}
+		line := strings.TrimSpace(string(l))
+		if line == "" || strings.HasPrefix(line, "#") {
+			return nil
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		k, v, found := strings.Cut(line, "=")
+		k = strings.TrimSpace(k)
+		if !found || k == "" {
+			return fmt.Errorf("'%s' is not in KEY=VALUE format", line)
+		}
+
+		v, err := parseEnvValue(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("value of '%s': %w", k, err)
+		}
+		ret[k] = v
+		return nil
+	}); err != nil {
+		return nil, WrErr(fmt.Errorf("parsing env file '%s': %w", path, err))
+	}
+	return ret, nil
+}
+
+func parseEnvValue(v string) (string, error) {
+	if v == "" {
+		return "", nil
+	}
+
+	switch v[0] {
+	case '\'':
+		end := strings.IndexByte(v[1:], '\'')
+		if end < 0 {
+			return "", errors.New("unterminated single quote")
+		}
+		return v[1 : end+1], nil
+
+	case '"':
+		var sb strings.Builder
+		for i := 1; i < len(v); i++ {
+			switch c := v[i]; {
+			case c == '"':
+				return sb.String(), nil
+			case c == '\\' && i+1 < len(v):
+				i++
+				switch v[i] {
+				case 'n':
+					sb.WriteByte('\n')
+				case 't':
+					sb.WriteByte('\t')
+				case 'r':
+					sb.WriteByte('\r')
+				default:
+					sb.WriteByte(v[i])
+				}
+			default:
+				sb.WriteByte(c)
+			}
+		}
+		return "", errors.New("unterminated double quote")
+
+	default:
+		if i := strings.Index(v, " #"); i >= 0 {
+			v = v[:i]
+		}
+		return strings.TrimSpace(v), nil
+	}
+}
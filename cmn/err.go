@@ -1,7 +1,10 @@
 package cmn //nolint:revive
 
 import (
+	"errors"
 	"fmt"
+	"runtime/debug"
+	"strings"
 
 	"golang.org/x/xerrors"
 )
@@ -48,3 +51,105 @@ func (e *cmnErr) FormatError(p xerrors.Printer) error {
 	}
 	return nil
 }
+
+// like WrErr, but records the frame `skip` levels above the caller
+func wrErrSkip(err error, skip int) error {
+	return &cmnErr{err: err, frame: xerrors.Caller(skip + 1)}
+}
+
+// ErrJSON is a JSON-serializable rendition of an error
+type ErrJSON struct {
+	Error  string   `json:"error"`
+	Frames []string `json:"frames,omitempty"` // outermost first, as `function file:line`
+}
+
+// ErrToJSON renders err as an ErrJSON, collecting the frames recorded by
+// WrErr anywhere in its Unwrap() chain
+func ErrToJSON(err error) ErrJSON {
+	if err == nil {
+		return ErrJSON{}
+	}
+	ej := ErrJSON{Error: err.Error()}
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if ce, isCmnErr := e.(*cmnErr); isCmnErr {
+			fp := &framePrinter{}
+			ce.frame.Format(fp)
+			if f := strings.Join(strings.Fields(fp.String()), " "); f != "" {
+				ej.Frames = append(ej.Frames, f)
+			}
+		}
+	}
+	return ej
+}
+
+type framePrinter struct{ strings.Builder }
+
+func (p *framePrinter) Print(args ...interface{})                 { fmt.Fprint(p, args...) }
+func (p *framePrinter) Printf(format string, args ...interface{}) { fmt.Fprintf(p, format, args...) }
+func (p *framePrinter) Detail() bool                              { return true }
+
+// RecoverToErr converts the result of a recover() into a framed error
+// carrying the panic value and the stack at the time of the panic. It must be
+// called from within the deferred function. A nil input returns nil.
+func RecoverToErr(r interface{}) error {
+	if r == nil {
+		return nil
+	}
+	if err, isErr := r.(error); isErr {
+		return wrErrSkip(fmt.Errorf("panic encountered: %w\n%s", err, debug.Stack()), 1)
+	}
+	return wrErrSkip(fmt.Errorf("panic encountered: %v\n%s", r, debug.Stack()), 1)
+}
+
+// WithRecover wraps fn such that a panic within it is returned as an error
+// ( see RecoverToErr ) instead of unwinding further
+func WithRecover(fn func() error) func() error {
+	return func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = RecoverToErr(r)
+			}
+		}()
+		return fn()
+	}
+}
+
+// Errors aggregates multiple errors into one. Its plain rendition lists the
+// messages separated by `; `, while %+v renders every error with its detail.
+// It supports errors.Is/As via a multi-error Unwrap.
+type Errors []error
+
+var _ error = Errors{}
+var _ fmt.Formatter = Errors{}
+
+func (e Errors) Error() string { //nolint:revive
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e Errors) Unwrap() []error { return e } //nolint:revive
+
+func (e Errors) Format(s fmt.State, v rune) { //nolint:revive
+	if v != 'v' || !s.Flag('+') {
+		fmt.Fprint(s, e.Error())
+		return
+	} else if len(e) == 1 {
+		fmt.Fprintf(s, "%+v", e[0])
+		return
+	}
+	fmt.Fprintf(s, "%d errors:", len(e))
+	for i, err := range e {
+		fmt.Fprintf(s, "\n--- error %d: %+v", i+1, err)
+	}
+}
+
+// ErrOrNil returns nil for an empty Errors, and the Errors otherwise
+func (e Errors) ErrOrNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
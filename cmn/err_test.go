@@ -0,0 +1,54 @@
+package cmn
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestRecoverToErr(t *testing.T) {
+	if RecoverToErr(nil) != nil {
+		t.Fatal("a nil recover() must yield a nil error")
+	}
+
+	var err error
+	var line int
+	func() {
+		_, _, line, _ = runtime.Caller(0)
+		defer func() { err = RecoverToErr(recover()) }() // must stay on the line right after runtime.Caller
+		panic("boom")
+	}()
+
+	if !strings.HasPrefix(err.Error(), "panic encountered: boom\n") {
+		t.Errorf("unexpected error message: %s", err)
+	}
+	assertCallerFrame(t, err, fmt.Sprintf("err_test.go:%d", line+1))
+}
+
+func TestWithRecover(t *testing.T) {
+	sentinel := errors.New("sentinel")
+
+	err := WithRecover(func() error { panic(sentinel) })()
+	if !errors.Is(err, sentinel) {
+		t.Fatalf("panic value not reachable via errors.Is: %v", err)
+	}
+	if !strings.HasPrefix(err.Error(), "panic encountered: sentinel\n") {
+		t.Errorf("unexpected error message: %s", err)
+	}
+	if len(ErrToJSON(err).Frames) == 0 {
+		t.Errorf("error carries no frames: %+v", err)
+	}
+	// the stack at the time of the panic leads to the panicking function
+	if !strings.Contains(err.Error(), "cmn.TestWithRecover.func1()") {
+		t.Errorf("stack of the panic missing from the error: %s", err)
+	}
+
+	if err := WithRecover(func() error { return sentinel })(); err != sentinel { //nolint:errorlint
+		t.Errorf("error of a non-panicking fn altered: %v", err)
+	}
+	if err := WithRecover(func() error { return nil })(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
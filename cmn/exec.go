@@ -0,0 +1,90 @@
+package cmn
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// how much of a failed child's stderr ends up in the error
+const cmdStderrTail = 4096
+
+// RunCmd runs the named program to completion, returning its stdout. On
+// failure the error carries the exit status and the tail of the stderr
+// output. Cancellation of ctx kills the child.
+func RunCmd(ctx context.Context, name string, args ...string) (string, error) {
+	var stdout bytes.Buffer
+	err := runCmd(ctx, &stdout, io.Discard, name, args)
+	return stdout.String(), err
+}
+
+// RunCmdStream is RunCmd copying the child's stdout and stderr to the
+// supplied writers ( either may be nil, discarding the output ) as it is
+// produced. The tail of stderr is still included in an eventual error.
+func RunCmdStream(ctx context.Context, stdout, stderr io.Writer, name string, args ...string) error {
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	if stderr == nil {
+		stderr = io.Discard
+	}
+	return runCmd(ctx, stdout, stderr, name, args)
+}
+
+func runCmd(ctx context.Context, stdout, stderr io.Writer, name string, args []string) error {
+	tail := &tailBuffer{max: cmdStderrTail}
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = stdout
+	cmd.Stderr = TeeWriter(stderr, tail)
+	// do not hang on descendants holding on to our pipes after a kill
+	cmd.WaitDelay = 5 * time.Second
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			err = fmt.Errorf("%w ( %w )", ctx.Err(), err)
+		}
+		msg := fmt.Sprintf("command '%s' failed: %s", strings.Join(append([]string{name}, args...), " "), err)
+		if se := strings.TrimSpace(tail.String()); se != "" {
+			msg += "\nstderr:\n" + se
+		}
+		return wrErrSkip(&cmdError{msg: msg, err: err}, 2) // the caller of RunCmd / RunCmdStream
+	}
+	return nil
+}
+
+// keeps the original error ( e.g. *exec.ExitError ) reachable via errors.As
+type cmdError struct {
+	msg string
+	err error
+}
+
+func (e *cmdError) Error() string { return e.msg }
+func (e *cmdError) Unwrap() error { return e.err }
+
+// retains only the last max bytes written
+type tailBuffer struct {
+	max       int
+	buf       []byte
+	truncated bool
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.buf = append(t.buf, p...)
+	if over := len(t.buf) - t.max; over > 0 {
+		t.buf = append(t.buf[:0], t.buf[over:]...)
+		t.truncated = true
+	}
+	return len(p), nil
+}
+
+func (t *tailBuffer) String() string {
+	if t.truncated {
+		return "..." + string(t.buf)
+	}
+	return string(t.buf)
+}
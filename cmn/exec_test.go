@@ -0,0 +1,68 @@
+package cmn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+const missingProgram = "cmn-test-no-such-program"
+
+func TestRunCmdErrorFrame(t *testing.T) {
+	_, _, line, _ := runtime.Caller(0)
+	_, err := RunCmd(context.Background(), missingProgram, "arg") // must stay on the line right after runtime.Caller
+	assertCallerFrame(t, err, fmt.Sprintf("exec_test.go:%d", line+1))
+
+	if !strings.Contains(err.Error(), "command '"+missingProgram+" arg' failed") {
+		t.Errorf("unexpected error message: %s", err)
+	}
+
+	_, _, line, _ = runtime.Caller(0)
+	err = RunCmdStream(context.Background(), nil, nil, missingProgram) // must stay on the line right after runtime.Caller
+	assertCallerFrame(t, err, fmt.Sprintf("exec_test.go:%d", line+1))
+}
+
+// the first ( outermost ) frame of err must point at the given file:line
+func assertCallerFrame(t *testing.T, err error, fileLine string) {
+	t.Helper()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	frames := ErrToJSON(err).Frames
+	if len(frames) == 0 {
+		t.Fatalf("error carries no frames: %+v", err)
+	}
+	if !strings.HasSuffix(frames[0], "/"+fileLine) {
+		t.Errorf("error framed at '%s', expected the caller '%s'", frames[0], fileLine)
+	}
+}
+
+func TestRunCmdCancelled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// the test binary itself, lingering in the helper below
+	t.Setenv("CMN_TEST_SLEEP", "1")
+	_, err := RunCmd(ctx, os.Args[0], "-test.run=^TestRunCmdSleepHelper$")
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("cancellation not reachable via errors.Is: %v", err)
+	}
+	var ee *exec.ExitError
+	if !errors.As(err, &ee) {
+		t.Errorf("exit error not reachable via errors.As: %v", err)
+	}
+}
+
+func TestRunCmdSleepHelper(t *testing.T) {
+	if os.Getenv("CMN_TEST_SLEEP") == "" {
+		t.Skip("subprocess of TestRunCmdCancelled")
+	}
+	time.Sleep(time.Minute)
+}
@@ -0,0 +1,115 @@
+package cmn
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// AtomicWriteFile writes data to a temporary file in the same directory as
+// path, and then renames it into place. The temporary name is dot-prefixed
+// and does not retain the original extension, so that directory scanners
+// keyed on extension ( e.g. node_exporter's textfile collector ) never see
+// a partial file.
+func AtomicWriteFile(path string, data []byte, perm os.FileMode) (err error) {
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, "."+base+"."+NewID()+".tmp"), os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return WrErr(err)
+	}
+	defer func() {
+		if err != nil {
+			f.Close()           //nolint:errcheck
+			os.Remove(f.Name()) //nolint:errcheck
+		}
+	}()
+
+	if _, err = f.Write(data); err != nil {
+		return WrErr(err)
+	}
+	if err = f.Sync(); err != nil {
+		return WrErr(err)
+	}
+	if err = f.Chmod(perm); err != nil {
+		return WrErr(err)
+	}
+	if err = f.Close(); err != nil {
+		return WrErr(err)
+	}
+	if err = os.Rename(f.Name(), path); err != nil {
+		return WrErr(err)
+	}
+	return nil
+}
+
+// OpenForWrite creates or truncates path for writing, creating any missing
+// parent directories ( with mode 0755, subject to the process umask ) first.
+func OpenForWrite(path string, perm os.FileMode) (*os.File, error) {
+	return openCreatingDirs(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+}
+
+// OpenForAppend is OpenForWrite, but preserving and appending to any existing content
+func OpenForAppend(path string, perm os.FileMode) (*os.File, error) {
+	return openCreatingDirs(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, perm)
+}
+
+func openCreatingDirs(path string, flag int, perm os.FileMode) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, WrErr(err)
+	}
+	f, err := os.OpenFile(path, flag, perm)
+	if err != nil {
+		return nil, WrErr(err)
+	}
+	return f, nil
+}
+
+// maxFilenameBytes is the common single path component limit of ext4, APFS, NTFS, etc
+const maxFilenameBytes = 255
+
+// SanitizeFilename turns s into a string safe to use as a single path
+// component on any common platform: path separators, control characters and
+// those illegal on Windows ( <>:"|?* ) are replaced by `_`, trailing dots
+// and spaces are removed, Windows' reserved device names ( CON, NUL, COM1,
+// etc, regardless of case or extension ) are prefixed by `_`, and the
+// result is truncated to 255 bytes on a UTF-8 boundary. Only the ASCII
+// spellings of the device names are recognized: look-alikes such as `COM¹`
+// ( which some Windows versions treat as reserved too ) pass through
+// unchanged. An input reducing to nothing, `.` or `..` yields `_`. Unlike
+// SanitizeMetricName this is not meant to be lossy beyond what safety
+// demands.
+func SanitizeFilename(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r < 0x20 || r == 0x7f || strings.ContainsRune(`/\<>:"|?*`, r) {
+			r = '_'
+		}
+		b.WriteRune(r)
+	}
+	out := strings.TrimRight(b.String(), ". ")
+
+	if out == "" || out == "." || out == ".." {
+		return "_"
+	}
+
+	stem, _, _ := strings.Cut(out, ".")
+	switch u := strings.ToUpper(strings.TrimRight(stem, " ")); {
+	case u == "CON", u == "PRN", u == "AUX", u == "NUL",
+		len(u) == 4 && (strings.HasPrefix(u, "COM") || strings.HasPrefix(u, "LPT")) && u[3] >= '0' && u[3] <= '9':
+		out = "_" + out
+	}
+
+	if len(out) > maxFilenameBytes {
+		cut := maxFilenameBytes
+		for cut > 0 && !utf8.RuneStart(out[cut]) {
+			cut--
+		}
+		out = strings.TrimRight(out[:cut], ". ")
+	}
+	return out
+}
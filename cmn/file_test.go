@@ -0,0 +1,48 @@
+package cmn
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSanitizeFilename(t *testing.T) {
+	for _, tc := range []struct{ in, out string }{
+		// Windows' reserved device names, regardless of case or extension
+		{"CON", "_CON"},
+		{"con", "_con"},
+		{"con.txt", "_con.txt"},
+		{"Nul.tar.gz", "_Nul.tar.gz"},
+		{"COM1", "_COM1"},
+		{"lpt9.log", "_lpt9.log"},
+		{"aux .txt", "_aux .txt"},
+		// merely resembling a reserved name
+		{"CONSOLE", "CONSOLE"},
+		{"COM10", "COM10"},
+		{"xcon.txt", "xcon.txt"},
+		// non-ASCII look-alikes are not recognized
+		{"COM¹", "COM¹"},
+		{"LPT².log", "LPT².log"},
+		// traversal and illegal characters
+		{"..", "_"},
+		{".", "_"},
+		{"", "_"},
+		{"../../etc/passwd", ".._.._etc_passwd"},
+		{`a\b:c*d?e"f<g>h|i`, "a_b_c_d_e_f_g_h_i"},
+		{"tab\there\x7f", "tab_here_"},
+		{"trailing. . ", "trailing"},
+		{"ünïcödé.txt", "ünïcödé.txt"},
+	} {
+		if got := SanitizeFilename(tc.in); got != tc.out {
+			t.Errorf("SanitizeFilename(%q) = %q, expected %q", tc.in, got, tc.out)
+		}
+	}
+}
+
+func TestSanitizeFilenameLength(t *testing.T) {
+	// a multi-byte rune straddling the limit is dropped whole
+	got := SanitizeFilename(strings.Repeat("a", maxFilenameBytes-1) + "é" + "tail")
+	if len(got) != maxFilenameBytes-1 || !utf8.ValidString(got) {
+		t.Errorf("unexpected truncation to %d bytes, valid UTF-8: %t", len(got), utf8.ValidString(got))
+	}
+}
@@ -0,0 +1,102 @@
+package cmn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Group is an errgroup-like collection of goroutines working on a common
+// task: the first one to return an error cancels the shared context, and that
+// error is what Wait() returns. See WG for a variant which does not cancel.
+type Group struct {
+	ctx     context.Context
+	cancel  context.CancelCauseFunc
+	timeout time.Duration
+	wg      sync.WaitGroup
+	errOnce sync.Once
+	err     error
+}
+
+// NewGroup returns a Group and the context its goroutines should observe
+func NewGroup(ctx context.Context) (*Group, context.Context) {
+	gctx, cancel := context.WithCancelCause(ctx)
+	return &Group{ctx: gctx, cancel: cancel}, gctx
+}
+
+// TimeoutGroup is NewGroup with an overall time budget: once total elapses
+// the context is cancelled and Wait() returns a framed timeout error ( unless
+// a goroutine failed first )
+func TimeoutGroup(ctx context.Context, total time.Duration) (*Group, context.Context) {
+	tctx, tcancel := context.WithTimeout(ctx, total)
+	g, gctx := NewGroup(tctx)
+	g.timeout = total
+	cancel := g.cancel
+	g.cancel = func(cause error) {
+		cancel(cause)
+		tcancel()
+	}
+	return g, gctx
+}
+
+// Go runs fn in a new goroutine
+func (g *Group) Go(fn func() error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if err := fn(); err != nil {
+			g.errOnce.Do(func() {
+				g.err = err
+				g.cancel(err)
+			})
+		}
+	}()
+}
+
+// Wait blocks until all goroutines have returned, then returns the first
+// error encountered, if any
+func (g *Group) Wait() error {
+	g.wg.Wait()
+
+	// a deadline hit is only reported if it was the reason things stopped
+	if g.timeout > 0 && errors.Is(context.Cause(g.ctx), context.DeadlineExceeded) &&
+		(g.err == nil || errors.Is(g.err, context.DeadlineExceeded)) {
+		g.err = WrErr(fmt.Errorf("group exceeded its total time budget of %s: %w", HumanDuration(g.timeout), context.DeadlineExceeded))
+	}
+
+	g.cancel(nil)
+	return g.err
+}
+
+// WG is a sync.WaitGroup collecting the errors of its goroutines. Unlike
+// Group there is no shared context and no early cancellation: every goroutine
+// runs to completion, and Wait() reports all the failures, not just the first.
+type WG struct {
+	wg   sync.WaitGroup
+	mu   sync.Mutex
+	errs Errors
+}
+
+// Go runs fn in a new goroutine
+func (w *WG) Go(fn func() error) {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		if err := fn(); err != nil {
+			w.mu.Lock()
+			w.errs = append(w.errs, err)
+			w.mu.Unlock()
+		}
+	}()
+}
+
+// Wait blocks until all goroutines have returned, then returns their errors
+// as an Errors in order of occurrence, or nil if there were none
+func (w *WG) Wait() error {
+	w.wg.Wait()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.errs.ErrOrNil()
+}
@@ -0,0 +1,183 @@
+package cmn
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// HashStable returns the hex-encoded SHA-256 of a canonical encoding of v.
+// The result is deterministic across runs and architectures: map entries are
+// hashed in sorted key order, all signed integer types hash identically for
+// the same value ( likewise unsigned integers and floats ), pointers and
+// interfaces are followed, structs contribute their exported fields by name,
+// and values implementing encoding.TextMarshaler ( e.g. time.Time ) hash
+// their text form. Channels, funcs and unsafe pointers are not supported and
+// result in an error, as does a value referencing itself ( through a pointer,
+// map or slice ).
+func HashStable(v interface{}) (string, error) {
+	h := sha256.New()
+	if err := hashStableValue(h, reflect.ValueOf(v), make(refPath)); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+func hashStableValue(h hash.Hash, rv reflect.Value, path refPath) error {
+	var scratch [8]byte
+	writeUint := func(tag byte, u uint64) {
+		binary.BigEndian.PutUint64(scratch[:], u)
+		h.Write([]byte{tag})
+		h.Write(scratch[:])
+	}
+	writeBytes := func(tag byte, b []byte) {
+		writeUint(tag, uint64(len(b)))
+		h.Write(b)
+	}
+
+	if !rv.IsValid() {
+		h.Write([]byte{'n'})
+		return nil
+	}
+
+	if rv.Type().Implements(textMarshalerType) && rv.CanInterface() &&
+		!((rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface) && rv.IsNil()) {
+		t, err := rv.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return WrErr(err)
+		}
+		writeBytes('t', t)
+		return nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			h.Write([]byte{'n'})
+			return nil
+		}
+		if rv.Kind() == reflect.Ptr {
+			leave, err := path.enter(rv)
+			if err != nil {
+				return err
+			}
+			defer leave()
+		}
+		return hashStableValue(h, rv.Elem(), path)
+	case reflect.Bool:
+		if rv.Bool() {
+			writeUint('b', 1)
+		} else {
+			writeUint('b', 0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		writeUint('i', uint64(rv.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		writeUint('u', rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		writeUint('f', math.Float64bits(rv.Float()))
+	case reflect.Complex64, reflect.Complex128:
+		c := rv.Complex()
+		writeUint('c', math.Float64bits(real(c)))
+		writeUint('c', math.Float64bits(imag(c)))
+	case reflect.String:
+		writeBytes('s', []byte(rv.String()))
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, rv.Len())
+			reflect.Copy(reflect.ValueOf(b), rv)
+			writeBytes('y', b)
+			return nil
+		}
+		if rv.Kind() == reflect.Slice && rv.Len() > 0 {
+			leave, err := path.enter(rv)
+			if err != nil {
+				return err
+			}
+			defer leave()
+		}
+		writeUint('l', uint64(rv.Len()))
+		for i := 0; i < rv.Len(); i++ {
+			if err := hashStableValue(h, rv.Index(i), path); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if !rv.IsNil() {
+			leave, err := path.enter(rv)
+			if err != nil {
+				return err
+			}
+			defer leave()
+		}
+		writeUint('m', uint64(rv.Len()))
+		if rv.Type().Key().Kind() == reflect.String {
+			for _, k := range SortedMapKeys(rv.Interface()) {
+				writeBytes('s', []byte(k))
+				if err := hashStableValue(h, rv.MapIndex(reflect.ValueOf(k).Convert(rv.Type().Key())), path); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		// arbitrary keys: order by their canonical encoding
+		type kv struct {
+			enc []byte
+			val reflect.Value
+		}
+		entries := make([]kv, 0, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			kh := &bufHash{}
+			if err := hashStableValue(kh, iter.Key(), path); err != nil {
+				return err
+			}
+			entries = append(entries, kv{enc: kh.Bytes(), val: iter.Value()})
+		}
+		sort.Slice(entries, func(i, j int) bool { return bytes.Compare(entries[i].enc, entries[j].enc) < 0 })
+		for _, e := range entries {
+			h.Write(e.enc)
+			if err := hashStableValue(h, e.val, path); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		rt := rv.Type()
+		var exported int
+		for i := 0; i < rt.NumField(); i++ {
+			if rt.Field(i).IsExported() {
+				exported++
+			}
+		}
+		writeUint('r', uint64(exported))
+		for i := 0; i < rt.NumField(); i++ {
+			if !rt.Field(i).IsExported() {
+				continue
+			}
+			writeBytes('s', []byte(rt.Field(i).Name))
+			if err := hashStableValue(h, rv.Field(i), path); err != nil {
+				return err
+			}
+		}
+	default:
+		return WrErr(fmt.Errorf("unable to stably hash values of type %s", rv.Type()))
+	}
+
+	return nil
+}
+
+// a hash.Hash that simply accumulates its input
+type bufHash struct{ bytes.Buffer }
+
+func (*bufHash) Sum(b []byte) []byte { return b }
+func (*bufHash) Size() int           { return 0 }
+func (*bufHash) BlockSize() int      { return 1 }
@@ -0,0 +1,73 @@
+package cmn
+
+import (
+	"strings"
+	"testing"
+)
+
+type hashNode struct {
+	Name string
+	Next *hashNode
+	Any  interface{}
+}
+
+func TestHashStable(t *testing.T) {
+	a, err := HashStable(map[string]interface{}{"int": 1, "uint": uint8(2), "list": []string{"x", "y"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := HashStable(map[string]interface{}{"list": []string{"x", "y"}, "uint": uint64(2), "int": int64(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != b {
+		t.Errorf("equivalent values hashed differently: %s vs %s", a, b)
+	}
+
+	if _, err := HashStable(make(chan int)); err == nil {
+		t.Error("expected an error hashing a channel")
+	}
+
+	shared := &hashNode{Name: "leaf"}
+	if _, err := HashStable(&hashNode{Next: shared, Any: []interface{}{shared, shared}}); err != nil {
+		t.Errorf("unexpected error for a repeated but acyclic pointer: %s", err)
+	}
+}
+
+func TestHashStableCycle(t *testing.T) {
+	selfPtr := &hashNode{Name: "self"}
+	selfPtr.Next = selfPtr
+
+	indirect := &hashNode{Name: "a", Next: &hashNode{Name: "b"}}
+	indirect.Next.Next = indirect
+
+	selfMap := map[string]interface{}{}
+	selfMap["me"] = selfMap
+
+	selfSlice := []interface{}{nil}
+	selfSlice[0] = selfSlice
+
+	selfKeyed := map[interface{}]int{}
+	selfKeyed[&hashNode{Any: selfKeyed}] = 1
+
+	for _, tc := range []struct {
+		name string
+		v    interface{}
+	}{
+		{"pointer to itself", selfPtr},
+		{"pointer through another", indirect},
+		{"map containing itself", selfMap},
+		{"slice containing itself", selfSlice},
+		{"map keyed by itself", selfKeyed},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := HashStable(tc.v)
+			if err == nil {
+				t.Fatalf("expected a cycle error, got hash %s", h)
+			}
+			if !strings.Contains(err.Error(), "cycle detected") {
+				t.Errorf("unexpected error: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package cmn
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IsRetryableStatus reports whether an HTTP response status signifies a
+// transient condition worth retrying: 408, 429, 500, 502, 503 and 504
+func IsRetryableStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout,
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// DoHTTP executes req via client ( http.DefaultClient when nil ), retrying
+// transport errors and IsRetryableStatus responses according to policy.
+// Only idempotent requests are retried: GET, HEAD, OPTIONS, TRACE, PUT and
+// DELETE, as well as any method carrying an Idempotency-Key header. A
+// request with a body is retried only when it can be rewound via GetBody
+// ( as is the case with http.NewRequest given a bytes/strings reader ). A
+// Retry-After header ( in seconds or as an HTTP date ) takes the place of the
+// backoff delay. As with http.Client.Do, the final response is returned
+// regardless of its status, while errors are framed and carry the number of
+// attempts made. Cancellation of ctx aborts both requests and waits.
+func DoHTTP(ctx context.Context, client *http.Client, req *http.Request, policy RetryPolicy) (*http.Response, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	maxAttempts := policy.maxAttempts()
+	if !isIdempotentRequest(req) || (req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+		maxAttempts = 1
+	}
+	b := policy.backoff()
+
+	for attempt := 1; ; attempt++ {
+		r := req.Clone(ctx)
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, WrErr(fmt.Errorf("rewinding request body for attempt %d: %w", attempt, err))
+			}
+			r.Body = body
+		}
+
+		resp, err := client.Do(r)
+		if err != nil {
+			if ctx.Err() != nil || attempt >= maxAttempts {
+				return nil, WrErr(fmt.Errorf("%s %s failed after %d attempt(s): %w", req.Method, req.URL.Redacted(), attempt, err))
+			}
+			resp = nil // a redirect failure: the body is already closed
+		} else if !IsRetryableStatus(resp.StatusCode) || attempt >= maxAttempts {
+			return resp, nil
+		}
+
+		delay := b.Next()
+		if resp != nil {
+			if ra, found := parseRetryAfter(resp.Header.Get("Retry-After")); found {
+				if policy.MaxRetryAfter > 0 && ra > policy.MaxRetryAfter {
+					return resp, nil
+				}
+				delay = ra
+			}
+			// allow the connection to be reused
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10)) //nolint:errcheck
+			resp.Body.Close()                                      //nolint:errcheck
+		}
+
+		t := time.NewTimer(delay)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return nil, WrErr(fmt.Errorf("%s %s abandoned after %d attempt(s): %w", req.Method, req.URL.Redacted(), attempt, ctx.Err()))
+		}
+	}
+}
+
+func isIdempotentRequest(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	_, hasKey := req.Header["Idempotency-Key"]
+	return hasKey
+}
+
+func parseRetryAfter(v string) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(v); err == nil {
+		d := time.Until(at)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
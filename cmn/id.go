@@ -0,0 +1,64 @@
+package cmn
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"sync"
+	"time"
+)
+
+// Crockford's base32: no I, L, O, U - case-insensitive-safe and sorts in ASCII order
+const idAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+var idState struct {
+	sync.Mutex
+	lastMs  uint64
+	lastRnd [10]byte
+}
+
+// NewID returns a 26 character ULID-compatible identifier: a 48 bit
+// millisecond timestamp followed by 80 random bits, in Crockford base32. IDs
+// are URL and filename safe, and sort lexically in creation order across
+// milliseconds. Within the same millisecond the IDs generated by a single
+// process are strictly increasing, while those of different processes are
+// unordered, but collide with negligible probability. The random component
+// is NOT meant to be a secret: do not use an ID as a token.
+func NewID() string { return NewIDAt(time.Now()) }
+
+// NewIDAt is NewID with the timestamp component taken from t
+func NewIDAt(t time.Time) string {
+	ms := uint64(t.UnixMilli()) & (1<<48 - 1)
+
+	var rnd [10]byte
+	idState.Lock()
+	if ms == idState.lastMs {
+		// monotonic increment, an overflow of 80 bits is not a practical concern
+		rnd = idState.lastRnd
+		for i := len(rnd) - 1; i >= 0; i-- {
+			rnd[i]++
+			if rnd[i] != 0 {
+				break
+			}
+		}
+	} else if _, err := rand.Read(rnd[:]); err != nil {
+		idState.Unlock()
+		panic(WrErr(err)) // a broken system RNG is not recoverable
+	}
+	idState.lastMs, idState.lastRnd = ms, rnd
+	idState.Unlock()
+
+	// 128 bits as two halves: 48+16 and 64
+	var buf [16]byte
+	binary.BigEndian.PutUint64(buf[0:8], ms<<16|uint64(rnd[0])<<8|uint64(rnd[1]))
+	copy(buf[8:], rnd[2:])
+	hi, lo := binary.BigEndian.Uint64(buf[0:8]), binary.BigEndian.Uint64(buf[8:])
+
+	// 26 chars * 5 bits = 130 bits: the leading char carries only the top 3
+	out := make([]byte, 26)
+	for i := 25; i >= 0; i-- {
+		out[i] = idAlphabet[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out)
+}
@@ -0,0 +1,40 @@
+package cmn
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ReadJSONFile decodes the JSON content of path into v. A missing file is
+// reported as an error satisfying errors.Is(err, fs.ErrNotExist), allowing
+// callers to tell a first run apart from a corrupt state.
+func ReadJSONFile(path string, v interface{}) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return WrErr(err)
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return WrErr(fmt.Errorf("decoding '%s': %w", path, err))
+	}
+	return nil
+}
+
+// WriteJSONFile is WriteJSONFilePerm with a mode of 0644
+func WriteJSONFile(path string, v interface{}) error {
+	return WriteJSONFilePerm(path, v, 0644)
+}
+
+// WriteJSONFilePerm atomically ( see AtomicWriteFile ) replaces path with the
+// indented JSON encoding of v, creating any missing parent directories first.
+func WriteJSONFilePerm(path string, v interface{}, perm os.FileMode) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return WrErr(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return WrErr(err)
+	}
+	return AtomicWriteFile(path, append(b, '\n'), perm)
+}
@@ -0,0 +1,42 @@
+package cmn
+
+import (
+	"fmt"
+	"strings"
+)
+
+// KVDupPolicy dictates how ParseKVWithPolicy treats a key appearing more than once
+type KVDupPolicy int
+
+//nolint:revive
+const (
+	KVDupError KVDupPolicy = iota
+	KVDupLastWins
+)
+
+// ParseKV parses a list of `k=v` pairs into a map, erroring on duplicate keys.
+// Keys are whitespace-trimmed and must be non-empty, values are taken verbatim
+// and may be empty or contain further `=` characters.
+func ParseKV(pairs []string) (map[string]string, error) {
+	return ParseKVWithPolicy(pairs, KVDupError)
+}
+
+// ParseKVWithPolicy is ParseKV with a configurable duplicate-key policy
+func ParseKVWithPolicy(pairs []string, dupPolicy KVDupPolicy) (map[string]string, error) {
+	ret := make(map[string]string, len(pairs))
+	for i, p := range pairs {
+		k, v, found := strings.Cut(p, "=")
+		if !found {
+			return nil, WrErr(fmt.Errorf("entry #%d '%s' is not in key=value format", i+1, p))
+		}
+		k = strings.TrimSpace(k)
+		if k == "" {
+			return nil, WrErr(fmt.Errorf("entry #%d '%s' has an empty key", i+1, p))
+		}
+		if _, seen := ret[k]; seen && dupPolicy == KVDupError {
+			return nil, WrErr(fmt.Errorf("entry #%d '%s' specifies duplicate key '%s'", i+1, p, k))
+		}
+		ret[k] = v
+	}
+	return ret, nil
+}
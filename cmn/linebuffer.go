@@ -0,0 +1,130 @@
+package cmn
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// LineBuffer is an io.Writer coalescing writes into larger batches of
+// complete lines, flushed to the underlying writer every flushEvery, or as
+// soon as maxLines lines accumulate. It is safe for concurrent use. Close()
+// flushes everything buffered ( including a trailing partial line ) and must
+// be called during shutdown to avoid losing output: within a ufcli action
+// register it via ufcli.CloseOnShutdown.
+type LineBuffer struct {
+	mu       sync.Mutex
+	w        io.Writer
+	buf      bytes.Buffer
+	lines    int
+	maxLines int
+	err      error // sticky error from the underlying writer
+	closed   bool
+	stop     chan struct{}
+	stopped  chan struct{}
+}
+
+var _ io.WriteCloser = &LineBuffer{}
+
+// NewLineBuffer returns a started LineBuffer. A flushEvery <= 0 disables
+// timed flushing, a maxLines <= 0 disables count-triggered flushing.
+func NewLineBuffer(w io.Writer, flushEvery time.Duration, maxLines int) *LineBuffer {
+	lb := &LineBuffer{
+		w:        w,
+		maxLines: maxLines,
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+
+	if flushEvery <= 0 {
+		close(lb.stopped)
+		return lb
+	}
+
+	go func() {
+		defer close(lb.stopped)
+		t := time.NewTicker(flushEvery)
+		defer t.Stop()
+		for {
+			select {
+			case <-lb.stop:
+				return
+			case <-t.C:
+				lb.mu.Lock()
+				lb.flushLocked(false) //nolint:errcheck
+				lb.mu.Unlock()
+			}
+		}
+	}()
+
+	return lb
+}
+
+// Write implements io.Writer. Errors from the underlying writer are returned
+// by the Write following the failed flush, and by every one thereafter.
+func (lb *LineBuffer) Write(p []byte) (int, error) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	if lb.closed {
+		return 0, WrErr(errors.New("write to closed LineBuffer"))
+	}
+	if lb.err != nil {
+		return 0, lb.err
+	}
+
+	lb.buf.Write(p)
+	lb.lines += bytes.Count(p, []byte{'\n'})
+	if lb.maxLines > 0 && lb.lines >= lb.maxLines {
+		lb.flushLocked(false) //nolint:errcheck
+	}
+	return len(p), nil
+}
+
+// Flush writes out all complete lines buffered so far
+func (lb *LineBuffer) Flush() error {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	return lb.flushLocked(false)
+}
+
+// Close stops the periodic flushing and writes out everything buffered
+func (lb *LineBuffer) Close() error {
+	lb.mu.Lock()
+	if lb.closed {
+		lb.mu.Unlock()
+		return nil
+	}
+	lb.closed = true
+	close(lb.stop)
+	lb.mu.Unlock()
+
+	<-lb.stopped
+
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	return lb.flushLocked(true)
+}
+
+func (lb *LineBuffer) flushLocked(includePartial bool) error {
+	if lb.err != nil {
+		return lb.err
+	}
+
+	n := lb.buf.Len()
+	if !includePartial {
+		n = bytes.LastIndexByte(lb.buf.Bytes(), '\n') + 1
+	}
+	if n == 0 {
+		return nil
+	}
+
+	if _, err := lb.w.Write(lb.buf.Next(n)); err != nil {
+		lb.err = WrErr(err)
+		return lb.err
+	}
+	lb.lines = bytes.Count(lb.buf.Bytes(), []byte{'\n'})
+	return nil
+}
@@ -0,0 +1,63 @@
+package cmn
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// DefaultMaxLine is the line length limit ScanLines applies when given a maxLine <= 0
+const DefaultMaxLine = 16 << 20
+
+// ScanLines invokes fn for every line of r, with the line terminator ( \n or
+// \r\n ) removed. Unlike a default bufio.Scanner it handles lines up to maxLine
+// bytes, erroring out on longer ones. The line passed to fn is only valid for
+// the duration of the call. Errors returned by fn abort the scan and are
+// returned annotated with the line number.
+func ScanLines(r io.Reader, maxLine int, fn func(line []byte) error) error {
+	if maxLine <= 0 {
+		maxLine = DefaultMaxLine
+	}
+	initial := 64 << 10
+	if initial > maxLine {
+		initial = maxLine
+	}
+
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, initial), maxLine)
+
+	var lineNo int
+	for s.Scan() {
+		lineNo++
+		if err := fn(s.Bytes()); err != nil {
+			return WrErr(fmt.Errorf("line %d: %w", lineNo, err))
+		}
+	}
+	if err := s.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return WrErr(fmt.Errorf("line %d exceeds the maximum length of %d bytes: %w", lineNo+1, maxLine, err))
+		}
+		return WrErr(err)
+	}
+	return nil
+}
+
+// ReadLines returns all lines of the file at path, see ScanLines
+func ReadLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, WrErr(err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	var lines []string
+	if err := ScanLines(f, 0, func(line []byte) error {
+		lines = append(lines, string(line))
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
@@ -0,0 +1,80 @@
+package cmn
+
+import (
+	"context"
+	"fmt"
+)
+
+// FieldLogger is the structured subset of a zap.SugaredLogger ( and thus of
+// a go-log ZapEventLogger ) that ContextLogger wraps
+type FieldLogger interface {
+	Debugw(msg string, keysAndValues ...interface{})
+	Infow(msg string, keysAndValues ...interface{})
+	Warnw(msg string, keysAndValues ...interface{})
+	Errorw(msg string, keysAndValues ...interface{})
+}
+
+type logFieldsKey struct{}
+
+// WithLogFields returns a ctx carrying the key/value pairs kv in addition to
+// those already accumulated in ctx, for ContextLogger to include in every
+// entry. Nested calls compose: new keys are appended, while a key already
+// present has its value overridden in place. Non-string keys are rendered via
+// fmt.Sprint, and a trailing key without a value gets a nil one.
+func WithLogFields(ctx context.Context, kv ...interface{}) context.Context {
+	if len(kv) == 0 {
+		return ctx
+	}
+	prev := LogFields(ctx)
+	fields := make([]interface{}, len(prev), len(prev)+len(kv)+1)
+	copy(fields, prev)
+
+	idx := make(map[string]int, (len(prev)+len(kv))/2)
+	for i := 0; i < len(fields); i += 2 {
+		idx[fields[i].(string)] = i
+	}
+	for i := 0; i < len(kv); i += 2 {
+		k := fmt.Sprint(kv[i])
+		var v interface{}
+		if i+1 < len(kv) {
+			v = kv[i+1]
+		}
+		if at, found := idx[k]; found {
+			fields[at+1] = v
+		} else {
+			idx[k] = len(fields)
+			fields = append(fields, k, v)
+		}
+	}
+	return context.WithValue(ctx, logFieldsKey{}, fields)
+}
+
+// LogFields returns the key/value pairs accumulated in ctx via WithLogFields,
+// the caller must not modify the result
+func LogFields(ctx context.Context) []interface{} {
+	f, _ := ctx.Value(logFieldsKey{}).([]interface{})
+	return f
+}
+
+// ContextLogger returns a FieldLogger prepending the LogFields of ctx to the
+// key/value pairs of every entry logged via l
+func ContextLogger(ctx context.Context, l FieldLogger) FieldLogger {
+	return &ctxLogger{l: l, fields: LogFields(ctx)}
+}
+
+type ctxLogger struct {
+	l      FieldLogger
+	fields []interface{}
+}
+
+func (c *ctxLogger) kv(kv []interface{}) []interface{} {
+	if len(c.fields) == 0 {
+		return kv
+	}
+	return append(append(make([]interface{}, 0, len(c.fields)+len(kv)), c.fields...), kv...)
+}
+
+func (c *ctxLogger) Debugw(msg string, kv ...interface{}) { c.l.Debugw(msg, c.kv(kv)...) }
+func (c *ctxLogger) Infow(msg string, kv ...interface{})  { c.l.Infow(msg, c.kv(kv)...) }
+func (c *ctxLogger) Warnw(msg string, kv ...interface{})  { c.l.Warnw(msg, c.kv(kv)...) }
+func (c *ctxLogger) Errorw(msg string, kv ...interface{}) { c.l.Errorw(msg, c.kv(kv)...) }
@@ -2,8 +2,11 @@ package cmn
 
 import (
 	"fmt"
+	"path/filepath"
 	"reflect"
+	"regexp"
 	"sort"
+	"strconv"
 )
 
 func SortedMapKeys(m interface{}) []string { //nolint:revive
@@ -18,3 +21,108 @@ func SortedMapKeys(m interface{}) []string { //nolint:revive
 	sort.Strings(avail)
 	return avail
 }
+
+// IsNil reports whether v is nil, including the case of a typed nil ( a nil
+// pointer, map, slice, func, chan or interface ) wrapped in a non-nil interface
+func IsNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan, reflect.Interface, reflect.UnsafePointer:
+		return rv.IsNil()
+	default:
+		return false
+	}
+}
+
+// FlattenMap turns a nested map into a single-level one with sep-joined keys,
+// e.g. {a:{b:1}} becomes {"a.b":1}. Slices and arrays are descended into using
+// the element index as key component. Nested maps must have string keys to be
+// descended into, everything else ( including nil values, empty maps and empty
+// slices ) is retained as a leaf value. A nil input yields an empty map.
+func FlattenMap(m map[string]interface{}, sep string) map[string]interface{} {
+	ret := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		flattenInto(ret, k, v, sep)
+	}
+	return ret
+}
+
+func flattenInto(dst map[string]interface{}, prefix string, v interface{}, sep string) {
+	rv := reflect.ValueOf(v)
+	switch {
+	case rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String && rv.Len() > 0:
+		iter := rv.MapRange()
+		for iter.Next() {
+			flattenInto(dst, prefix+sep+iter.Key().String(), iter.Value().Interface(), sep)
+		}
+	case (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) && rv.Len() > 0 && rv.Type().Elem().Kind() != reflect.Uint8:
+		for i := 0; i < rv.Len(); i++ {
+			flattenInto(dst, prefix+sep+strconv.Itoa(i), rv.Index(i).Interface(), sep)
+		}
+	default:
+		dst[prefix] = v
+	}
+}
+
+// DiffStrings compares two string sets: added are the elements only in b,
+// removed those only in a, and common the ones in both. Duplicates within
+// an input are disregarded, and every result is sorted.
+func DiffStrings(a, b []string) (added, removed, common []string) {
+	inA := make(map[string]struct{}, len(a))
+	for _, s := range a {
+		inA[s] = struct{}{}
+	}
+	inB := make(map[string]struct{}, len(b))
+	for _, s := range b {
+		inB[s] = struct{}{}
+	}
+
+	for _, s := range SortedMapKeys(inA) {
+		if _, found := inB[s]; found {
+			common = append(common, s)
+		} else {
+			removed = append(removed, s)
+		}
+	}
+	for _, s := range SortedMapKeys(inB) {
+		if _, found := inA[s]; !found {
+			added = append(added, s)
+		}
+	}
+	return added, removed, common
+}
+
+// MatchKeys returns the sorted keys of m matching the filepath.Match glob
+// pattern ( `*` and `?` not matching a `/`, `[...]` classes, `\` escaping ).
+// A malformed pattern is a framed error, even when m is empty.
+func MatchKeys[V any](m map[string]V, pattern string) ([]string, error) {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, WrErr(fmt.Errorf("invalid glob pattern '%s': %w", pattern, err))
+	}
+	return matchingKeys(m, func(k string) bool {
+		ok, _ := filepath.Match(pattern, k)
+		return ok
+	}), nil
+}
+
+// MatchKeysRegexp is MatchKeys with an unanchored regexp.Compile pattern
+func MatchKeysRegexp[V any](m map[string]V, pattern string) ([]string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, WrErr(fmt.Errorf("invalid regular expression '%s': %w", pattern, err))
+	}
+	return matchingKeys(m, re.MatchString), nil
+}
+
+func matchingKeys[V any](m map[string]V, match func(string) bool) []string {
+	var ret []string
+	for _, k := range SortedMapKeys(m) {
+		if match(k) {
+			ret = append(ret, k)
+		}
+	}
+	return ret
+}
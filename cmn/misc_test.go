@@ -0,0 +1,74 @@
+package cmn
+
+import (
+	"errors"
+	"io"
+	"slices"
+	"testing"
+)
+
+type isNilStruct struct{ p *int }
+
+func TestIsNil(t *testing.T) {
+	var (
+		nilPtr    *int
+		nilMap    map[string]int
+		nilSlice  []int
+		nilChan   chan int
+		nilFunc   func()
+		nilErrPtr *isNilErr
+		nilReader io.Reader
+	)
+	x := 1
+
+	for _, tc := range []struct {
+		name  string
+		v     interface{}
+		isNil bool
+	}{
+		{"untyped nil", nil, true},
+		{"nil pointer", nilPtr, true},
+		{"nil map", nilMap, true},
+		{"nil slice", nilSlice, true},
+		{"nil chan", nilChan, true},
+		{"nil func", nilFunc, true},
+		{"nil interface", nilReader, true},
+		{"typed nil pointer in an error", error(nilErrPtr), true},
+		{"pointer to a nil interface", &nilReader, false},
+		{"pointer", &x, false},
+		{"empty map", map[string]int{}, false},
+		{"empty slice", []int{}, false},
+		{"chan", make(chan int), false},
+		{"func", func() {}, false},
+		{"zero struct", isNilStruct{}, false},
+		{"zero int", 0, false},
+		{"empty string", "", false},
+		{"error", errors.New("x"), false},
+	} {
+		if got := IsNil(tc.v); got != tc.isNil {
+			t.Errorf("%s: IsNil(%#v) = %t, expected %t", tc.name, tc.v, got, tc.isNil)
+		}
+	}
+}
+
+// an error implementation with a pointer receiver, for the typed-nil case
+type isNilErr struct{}
+
+func (*isNilErr) Error() string { return "isNilErr" }
+
+func TestDiffStringsDuplicates(t *testing.T) {
+	added, removed, common := DiffStrings(
+		[]string{"b", "a", "b", "c", "a"},
+		[]string{"d", "c", "c", "b", "d", "e"},
+	)
+	if !slices.Equal(added, []string{"d", "e"}) ||
+		!slices.Equal(removed, []string{"a"}) ||
+		!slices.Equal(common, []string{"b", "c"}) {
+		t.Errorf("unexpected diff: added %q, removed %q, common %q", added, removed, common)
+	}
+
+	added, removed, common = DiffStrings([]string{"x", "x"}, nil)
+	if len(added) != 0 || !slices.Equal(removed, []string{"x"}) || len(common) != 0 {
+		t.Errorf("unexpected diff against nil: added %q, removed %q, common %q", added, removed, common)
+	}
+}
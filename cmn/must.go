@@ -0,0 +1,96 @@
+package cmn
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// The Must* family is intended for asserting invariants: converting values
+// already validated elsewhere, or compile-time constants. They panic with a
+// framed error naming the offending input and the caller's location.
+// Never use them on user-supplied or otherwise untrusted data.
+
+func mustPanic(kind, s string, err error) {
+	// skip mustPanic and the Must* function itself
+	panic(wrErrSkip(fmt.Errorf("invariant violated: unable to parse %q as %s: %w", s, kind, err), 2))
+}
+
+// MustAtoi is strconv.Atoi panicking on error
+func MustAtoi(s string) int {
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		mustPanic("int", s, err)
+	}
+	return v
+}
+
+// MustParseInt64 is strconv.ParseInt(s, 10, 64) panicking on error
+func MustParseInt64(s string) int64 {
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		mustPanic("int64", s, err)
+	}
+	return v
+}
+
+// MustParseUint64 is strconv.ParseUint(s, 10, 64) panicking on error
+func MustParseUint64(s string) uint64 {
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		mustPanic("uint64", s, err)
+	}
+	return v
+}
+
+// MustParseFloat64 is strconv.ParseFloat(s, 64) panicking on error
+func MustParseFloat64(s string) float64 {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		mustPanic("float64", s, err)
+	}
+	return v
+}
+
+// MustParseBool is strconv.ParseBool panicking on error
+func MustParseBool(s string) bool {
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		mustPanic("bool", s, err)
+	}
+	return v
+}
+
+// MustParseDuration is time.ParseDuration panicking on error
+func MustParseDuration(s string) time.Duration {
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		mustPanic("duration", s, err)
+	}
+	return v
+}
+
+// CastOr returns v as a T, or def when v does not hold a T ( including a nil
+// v, even for an interface or pointer T ). For an interface T any v
+// implementing it qualifies, a pointer T requires the exact pointer type.
+func CastOr[T any](v interface{}, def T) T {
+	if t, ok := v.(T); ok {
+		return t
+	}
+	return def
+}
+
+// MustCast is CastOr panicking, with a framed error naming the expected and
+// the actual type, where CastOr would return the default
+func MustCast[T any](v interface{}) T {
+	t, ok := v.(T)
+	if !ok {
+		panic(wrErrSkip(fmt.Errorf(
+			"invariant violated: value of type %T is not a %s",
+			v,
+			reflect.TypeOf((*T)(nil)).Elem(),
+		), 1))
+	}
+	return t
+}
@@ -0,0 +1,84 @@
+package cmn
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCastOr(t *testing.T) {
+	if v := CastOr(42, 0); v != 42 {
+		t.Errorf("CastOr of a matching type returned %v", v)
+	}
+	if v := CastOr("42", -1); v != -1 {
+		t.Errorf("CastOr of a mismatching type returned %v", v)
+	}
+	if v := CastOr(nil, "def"); v != "def" {
+		t.Errorf("CastOr of nil returned %q", v)
+	}
+
+	// interface T: any implementation qualifies
+	buf := new(bytes.Buffer)
+	if r := CastOr[io.Reader](buf, nil); r != buf {
+		t.Errorf("CastOr to an implemented interface returned %v", r)
+	}
+	if r := CastOr[io.Reader](42, nil); r != nil {
+		t.Errorf("CastOr to an unimplemented interface returned %v", r)
+	}
+	if r := CastOr[io.Reader](nil, buf); r != buf {
+		t.Errorf("CastOr of nil to an interface returned %v", r)
+	}
+
+	// pointer T: the exact pointer type only
+	x := 1
+	if p := CastOr[*int](&x, nil); p != &x {
+		t.Errorf("CastOr to a pointer returned %v", p)
+	}
+	if p := CastOr[*int](x, nil); p != nil {
+		t.Errorf("CastOr of a value to a pointer returned %v", p)
+	}
+	if p := CastOr[*int](nil, &x); p != &x {
+		t.Errorf("CastOr of nil to a pointer returned %v", p)
+	}
+	// a typed nil pointer is still of the right type
+	if p := CastOr[*int]((*int)(nil), &x); p != nil {
+		t.Errorf("CastOr of a typed nil pointer returned %v", p)
+	}
+}
+
+func TestMustCast(t *testing.T) {
+	if v := MustCast[error](io.EOF); v != io.EOF { //nolint:errorlint
+		t.Errorf("MustCast to an implemented interface returned %v", v)
+	}
+
+	var err error
+	var line int
+	func() {
+		defer func() {
+			r := recover()
+			var isErr bool
+			if err, isErr = r.(error); !isErr {
+				t.Fatalf("MustCast panicked with a non-error %v", r)
+			}
+		}()
+		_, _, line, _ = runtime.Caller(0)
+		MustCast[*bytes.Buffer]("a string") // must stay on the line right after runtime.Caller
+	}()
+
+	if msg := err.Error(); !strings.Contains(msg, "value of type string is not a *bytes.Buffer") {
+		t.Errorf("unexpected panic message: %s", msg)
+	}
+	assertCallerFrame(t, err, fmt.Sprintf("must_test.go:%d", line+1))
+
+	func() {
+		defer func() {
+			if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), "value of type <nil> is not a io.Reader") {
+				t.Errorf("unexpected panic for a nil value: %v", r)
+			}
+		}()
+		MustCast[io.Reader](nil)
+	}()
+}
@@ -0,0 +1,72 @@
+package cmn
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+)
+
+// seconds between the NTP ( 1900 ) and the unix ( 1970 ) epochs
+const ntpEpochOffset = 2208988800
+
+// ClockOffset queries the NTP server at addr ( host or host:port, the port
+// defaulting to 123 ) via a single SNTP exchange, returning how far the
+// local clock is off: a positive result means the local clock is behind.
+// Absent a ctx deadline the query times out after 5 seconds.
+func ClockOffset(ctx context.Context, addr string) (time.Duration, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "123")
+	}
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", addr)
+	if err != nil {
+		return 0, WrErr(err)
+	}
+	defer conn.Close() //nolint:errcheck
+	if dl, hasDeadline := ctx.Deadline(); hasDeadline {
+		conn.SetDeadline(dl) //nolint:errcheck
+	}
+
+	req := make([]byte, 48)
+	req[0] = 0x23 // LI 0, version 4, mode 3 ( client )
+	t1 := time.Now()
+	if _, err := conn.Write(req); err != nil {
+		return 0, WrErr(fmt.Errorf("querying NTP server %s: %w", addr, err))
+	}
+	resp := make([]byte, 48)
+	n, err := conn.Read(resp)
+	t4 := time.Now()
+	if err != nil {
+		return 0, WrErr(fmt.Errorf("querying NTP server %s: %w", addr, err))
+	}
+	if n < 48 || resp[0]&0x07 != 4 || resp[1] == 0 {
+		return 0, WrErr(fmt.Errorf("invalid or kiss-of-death response from NTP server %s", addr))
+	}
+
+	t2, t3 := ntpTime(resp[32:40]), ntpTime(resp[40:48])
+	if t3.IsZero() {
+		return 0, WrErr(fmt.Errorf("NTP server %s returned no transmit timestamp", addr))
+	}
+	return (t2.Sub(t1) + t3.Sub(t4)) / 2, nil
+}
+
+func ntpTime(b []byte) time.Time {
+	secs, frac := binary.BigEndian.Uint32(b[:4]), binary.BigEndian.Uint32(b[4:])
+	if secs == 0 && frac == 0 {
+		return time.Time{}
+	}
+	// RFC 4330: with the most significant bit unset the era rolled over ( in 2036 )
+	s := int64(secs)
+	if secs&0x80000000 == 0 {
+		s += 1 << 32
+	}
+	return time.Unix(s-ntpEpochOffset, int64((uint64(frac)*1e9)>>32))
+}
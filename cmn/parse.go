@@ -0,0 +1,87 @@
+package cmn
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ParseBool is a lenient strconv.ParseBool, additionally accepting yes/no,
+// y/n and on/off. Matching is case-insensitive and ignores surrounding
+// whitespace. An empty input is an error, see ParseBoolOr.
+func ParseBool(s string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "1", "t", "true", "y", "yes", "on":
+		return true, nil
+	case "0", "f", "false", "n", "no", "off":
+		return false, nil
+	default:
+		return false, WrErr(fmt.Errorf("unable to interpret %q as a boolean", s))
+	}
+}
+
+// ParseBoolOr is ParseBool returning def for empty ( or whitespace-only ) input
+func ParseBoolOr(s string, def bool) (bool, error) {
+	if strings.TrimSpace(s) == "" {
+		return def, nil
+	}
+	return ParseBool(s)
+}
+
+// ParseList splits s on any run of commas and/or whitespace ( including
+// newlines ), returning the non-empty items in order. There is no quoting
+// or escaping: an item can not itself contain a comma or whitespace.
+func ParseList(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+}
+
+// the layouts ParseTime tries, in order: those without a zone are UTC
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	time.DateOnly,
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+// ParseTime parses a timestamp in any of the formats commonly found in
+// configs and on command lines: RFC3339 ( with or without a `T` separator,
+// fractional seconds and a zone ), date-only `2006-01-02`, RFC1123 ( prefer
+// a numeric zone: see time.Parse on abbreviations ) or unix epoch seconds.
+// An epoch of more than 11 digits is taken to be in milliseconds. Inputs
+// lacking a zone, including date-only ones ( denoting midnight ), are
+// interpreted as UTC, regardless of the local timezone. Surrounding
+// whitespace is ignored.
+func ParseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, WrErr(errors.New("empty timestamp"))
+	}
+
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		if digits := strings.TrimPrefix(s, "-"); len(digits) > 11 {
+			return time.UnixMilli(n).UTC(), nil
+		}
+		return time.Unix(n, 0).UTC(), nil
+	}
+
+	for _, l := range timeLayouts {
+		if t, err := time.Parse(l, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, WrErr(fmt.Errorf(
+		"unable to parse timestamp '%s', tried unix epoch seconds/milliseconds and the layouts: %s",
+		s,
+		strings.Join(timeLayouts, " | "),
+	))
+}
@@ -0,0 +1,91 @@
+package cmn
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mattn/go-isatty"
+)
+
+// IsInteractive reports whether stderr is attached to a terminal
+func IsInteractive() bool {
+	fd := os.Stderr.Fd()
+	return isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd)
+}
+
+// Progress renders a single self-updating status line on stderr, when it is
+// a terminal ( see IsInteractive ), and does nothing otherwise, so it is safe
+// to leave in place for non-interactive runs. Add() is safe for concurrent use
+// and cheap: rendering happens at a fixed rate, independent of update volume.
+type Progress struct {
+	label    string
+	total    int64
+	count    atomic.Int64
+	w        io.Writer
+	stop     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+var spinnerFrames = []rune(`|/-\`)
+
+// NewProgress starts a progress display. A total of 0 means "unknown", in
+// which case only the running count is shown.
+func NewProgress(label string, total int64) *Progress {
+	p := &Progress{
+		label:   label,
+		total:   total,
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	if !IsInteractive() {
+		close(p.stopped)
+		return p
+	}
+
+	p.w = os.Stderr
+	go func() {
+		defer close(p.stopped)
+		t := time.NewTicker(100 * time.Millisecond)
+		defer t.Stop()
+		for frame := 0; ; frame++ {
+			select {
+			case <-p.stop:
+				p.render(' ')
+				fmt.Fprintln(p.w)
+				return
+			case <-t.C:
+				p.render(spinnerFrames[frame%len(spinnerFrames)])
+			}
+		}
+	}()
+
+	return p
+}
+
+// Add advances the progress count by n
+func (p *Progress) Add(n int64) { p.count.Add(n) }
+
+// Count returns the current progress count
+func (p *Progress) Count() int64 { return p.count.Load() }
+
+// Done stops the display, leaving the final state on screen. It is safe to
+// call multiple times.
+func (p *Progress) Done() {
+	p.stopOnce.Do(func() { close(p.stop) })
+	<-p.stopped
+}
+
+func (p *Progress) render(spin rune) {
+	c := p.count.Load()
+	if p.total > 0 {
+		fmt.Fprintf(p.w, "\r\033[K%c %s %d/%d (%.1f%%)", spin, p.label, c, p.total, 100*float64(c)/float64(p.total))
+	} else {
+		fmt.Fprintf(p.w, "\r\033[K%c %s %d", spin, p.label, c)
+	}
+}
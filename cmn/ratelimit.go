@@ -0,0 +1,88 @@
+package cmn
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimiter paces work to at most N events per time period, allowing bursts
+// of up to N events when idle. It is safe for concurrent use.
+type RateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	burst    int
+	tat      time.Time // theoretical arrival time of the next event
+}
+
+// NewRateLimiter returns a limiter allowing n events per period
+func NewRateLimiter(n int, per time.Duration) (*RateLimiter, error) {
+	if n <= 0 || per <= 0 {
+		return nil, WrErr(fmt.Errorf("invalid rate %d per %s: both must be positive", n, per))
+	}
+	return &RateLimiter{
+		interval: per / time.Duration(n),
+		burst:    n,
+	}, nil
+}
+
+// ParseRateLimiter constructs a limiter from a `N/period` spec, where period
+// is either a time.ParseDuration value ( `100/1m`, `5/1.5s` ) or a bare unit
+// implying a count of 1 ( `10/s`, `1000/h` ).
+func ParseRateLimiter(spec string) (*RateLimiter, error) {
+	nStr, perStr, found := strings.Cut(strings.TrimSpace(spec), "/")
+	if !found {
+		return nil, WrErr(fmt.Errorf("rate spec '%s' is not in N/period format", spec))
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(nStr))
+	if err != nil {
+		return nil, WrErr(fmt.Errorf("rate spec '%s' has an invalid count: %w", spec, err))
+	}
+	perStr = strings.TrimSpace(perStr)
+	if perStr != "" && (perStr[0] < '0' || perStr[0] > '9') && perStr[0] != '.' {
+		perStr = "1" + perStr
+	}
+	per, err := time.ParseDuration(perStr)
+	if err != nil {
+		return nil, WrErr(fmt.Errorf("rate spec '%s' has an invalid period: %w", spec, err))
+	}
+	return NewRateLimiter(n, per)
+}
+
+// Wait blocks until the next event is permitted, or returns a framed error
+// when ctx is done first
+func (rl *RateLimiter) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return WrErr(err)
+	}
+
+	rl.mu.Lock()
+	now := time.Now()
+	if rl.tat.Before(now) {
+		rl.tat = now
+	}
+	allowAt := rl.tat.Add(-time.Duration(rl.burst-1) * rl.interval)
+	rl.tat = rl.tat.Add(rl.interval)
+	rl.mu.Unlock()
+
+	delay := allowAt.Sub(now)
+	if delay <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		// give back our reservation
+		rl.mu.Lock()
+		rl.tat = rl.tat.Add(-rl.interval)
+		rl.mu.Unlock()
+		return WrErr(ctx.Err())
+	}
+}
@@ -0,0 +1,82 @@
+package cmn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultRetryAttempts is the attempt limit applied when given a RetryPolicy.MaxAttempts <= 0
+const DefaultRetryAttempts = 4
+
+// RetryPolicy governs the retries of Retryf and DoHTTP. The zero value is usable.
+type RetryPolicy struct {
+	MaxAttempts   int           // total attempts, including the first one, DefaultRetryAttempts when <= 0
+	Backoff       *Backoff      // template of the delays between attempts ( every call starts its own sequence ), defaults to ExponentialBackoff(500ms, 30s, 0.2)
+	MaxRetryAfter time.Duration // DoHTTP only: if set, a Retry-After asking to wait longer than this ends the retries, returning the response as-is
+}
+
+func (p RetryPolicy) maxAttempts() int {
+	if p.MaxAttempts <= 0 {
+		return DefaultRetryAttempts
+	}
+	return p.MaxAttempts
+}
+
+func (p RetryPolicy) backoff() *Backoff {
+	if p.Backoff == nil {
+		return ExponentialBackoff(500*time.Millisecond, 30*time.Second, 0.2)
+	}
+	return &Backoff{Base: p.Backoff.Base, Factor: p.Backoff.Factor, Max: p.Backoff.Max, Jitter: p.Backoff.Jitter}
+}
+
+type permanentErr struct{ err error }
+
+func (e *permanentErr) Error() string { return e.err.Error() }
+func (e *permanentErr) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying, see IsRetryable. The result is
+// transparent to errors.Is/As. A nil err returns nil.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentErr{err: err}
+}
+
+// IsRetryable reports whether err is worth retrying: every non-nil error is,
+// except those marked via Permanent and context cancellations/deadlines
+func IsRetryable(err error) bool {
+	return err != nil &&
+		!errors.As(err, new(*permanentErr)) &&
+		!errors.Is(err, context.Canceled) &&
+		!errors.Is(err, context.DeadlineExceeded)
+}
+
+// Retryf invokes fn until it succeeds, returns an error that is not
+// IsRetryable, or policy.MaxAttempts is reached, sleeping per policy.Backoff
+// in between. The final error is framed and annotated with label and the
+// number of attempts made. Cancellation of ctx during a backoff returns
+// promptly, with the ctx error wrapped alongside the last one of fn.
+func Retryf(ctx context.Context, policy RetryPolicy, label string, fn func(ctx context.Context) error) error {
+	maxAttempts, b := policy.maxAttempts(), policy.backoff()
+
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		if !IsRetryable(err) || attempt >= maxAttempts {
+			return WrErr(fmt.Errorf("%s failed after %d attempt(s): %w", label, attempt, err))
+		}
+
+		t := time.NewTimer(b.Next())
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return WrErr(fmt.Errorf("%s abandoned after %d attempt(s): %w ( last error: %w )", label, attempt, ctx.Err(), err))
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package cmn
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+)
+
+// the longest line a RingBuffer retains, the remainder is dropped
+const ringBufferMaxLine = 64 << 10
+
+// RingBuffer is an io.Writer retaining the most recent lines written to it,
+// e.g. for attaching the tail of some output to an error report. Memory use
+// is bounded regardless of the volume written: lines longer than 64KiB are
+// truncated. It is safe for concurrent use, though concurrent writes of
+// partial lines interleave.
+type RingBuffer struct {
+	mu      sync.Mutex
+	lines   []string
+	next    int  // slot of the upcoming line once lines is full
+	full    bool // wrapped around at least once
+	partial []byte
+	trunc   bool // the partial line exceeded ringBufferMaxLine
+}
+
+// NewRingBuffer returns a RingBuffer retaining the last n ( at least 1 ) lines
+func NewRingBuffer(n int) *RingBuffer {
+	if n < 1 {
+		n = 1
+	}
+	return &RingBuffer{lines: make([]string, 0, n)}
+}
+
+// Write implements io.Writer, it never fails
+func (rb *RingBuffer) Write(p []byte) (int, error) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		chunk := p
+		if i >= 0 {
+			chunk = p[:i]
+		}
+		if room := ringBufferMaxLine - len(rb.partial); len(chunk) > room {
+			chunk = chunk[:room]
+			rb.trunc = true
+		}
+		rb.partial = append(rb.partial, chunk...)
+		if i < 0 {
+			break
+		}
+		rb.push(strings.TrimSuffix(string(rb.partial), "\r"))
+		p = p[i+1:]
+	}
+	return n, nil
+}
+
+func (rb *RingBuffer) push(line string) {
+	if rb.trunc {
+		line += "..."
+	}
+	rb.partial, rb.trunc = rb.partial[:0], false
+
+	if !rb.full && len(rb.lines) < cap(rb.lines) {
+		rb.lines = append(rb.lines, line)
+		return
+	}
+	rb.full = true
+	rb.lines[rb.next] = line
+	rb.next = (rb.next + 1) % len(rb.lines)
+}
+
+// Lines returns the retained lines, oldest first, without their terminators.
+// A trailing incomplete line is included, but may displace the oldest one.
+func (rb *RingBuffer) Lines() []string {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	ret := make([]string, 0, len(rb.lines)+1)
+	ret = append(ret, rb.lines[rb.next:]...)
+	ret = append(ret, rb.lines[:rb.next]...)
+	if len(rb.partial) > 0 {
+		last := string(rb.partial)
+		if rb.trunc {
+			last += "..."
+		}
+		ret = append(ret, last)
+		if len(ret) > cap(rb.lines) {
+			ret = ret[1:]
+		}
+	}
+	return ret
+}
+
+// String returns Lines() joined by newlines
+func (rb *RingBuffer) String() string {
+	return strings.Join(rb.Lines(), "\n")
+}
@@ -0,0 +1,46 @@
+package cmn
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRingBufferWraparound(t *testing.T) {
+	rb := NewRingBuffer(3)
+
+	fmt.Fprint(rb, "one\ntwo\n")
+	if l := rb.Lines(); !slices.Equal(l, []string{"one", "two"}) {
+		t.Fatalf("unexpected lines before filling up: %q", l)
+	}
+
+	for i := 3; i <= 10; i++ {
+		fmt.Fprintf(rb, "line %d\n", i)
+		if l := rb.Lines(); len(l) != 3 || l[2] != fmt.Sprintf("line %d", i) {
+			t.Fatalf("unexpected lines after write %d: %q", i, l)
+		}
+	}
+	if l := rb.Lines(); !slices.Equal(l, []string{"line 8", "line 9", "line 10"}) {
+		t.Fatalf("unexpected lines after wrapping around: %q", l)
+	}
+
+	// a partial line displaces the oldest one, and is completed by a later write
+	fmt.Fprint(rb, "part")
+	if s := rb.String(); s != "line 9\nline 10\npart" {
+		t.Fatalf("unexpected rendition with a partial line: %q", s)
+	}
+	fmt.Fprint(rb, "ial\r\nlast\n")
+	if l := rb.Lines(); !slices.Equal(l, []string{"line 10", "partial", "last"}) {
+		t.Fatalf("unexpected lines after completing the partial one: %q", l)
+	}
+}
+
+func TestRingBufferLongLine(t *testing.T) {
+	rb := NewRingBuffer(2)
+	fmt.Fprint(rb, strings.Repeat("x", ringBufferMaxLine+10)+"\nshort\n")
+	l := rb.Lines()
+	if len(l) != 2 || l[0] != strings.Repeat("x", ringBufferMaxLine)+"..." || l[1] != "short" {
+		t.Fatalf("long line not truncated as expected ( got %d lines )", len(l))
+	}
+}
@@ -0,0 +1,128 @@
+package cmn
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Semaphore is a weighted counting semaphore, limiting access to a resource
+// across unrelated goroutines. Waiters are served in FIFO order: a large
+// request blocks smaller ones arriving after it, thus can not be starved.
+type Semaphore struct {
+	size    int
+	mu      sync.Mutex
+	cur     int
+	waiters list.List // of *semWaiter
+}
+
+type semWaiter struct {
+	n     int
+	ready chan struct{}
+}
+
+// NewSemaphore returns a Semaphore with a capacity of n ( at least 1 )
+func NewSemaphore(n int) *Semaphore {
+	if n < 1 {
+		n = 1
+	}
+	return &Semaphore{size: n}
+}
+
+// Acquire is AcquireN(ctx, 1)
+func (s *Semaphore) Acquire(ctx context.Context) error { return s.AcquireN(ctx, 1) }
+
+// Release is ReleaseN(1)
+func (s *Semaphore) Release() { s.ReleaseN(1) }
+
+// AcquireN blocks until a weight of n is available, or returns a framed error
+// when ctx is done first ( having acquired nothing ). Requesting a weight
+// below 1 or more than the capacity of the semaphore is an immediate error.
+func (s *Semaphore) AcquireN(ctx context.Context, n int) error {
+	if n < 1 {
+		return WrErr(fmt.Errorf("requested weight %d is not positive", n))
+	}
+	if n > s.size {
+		return WrErr(fmt.Errorf("requested weight %d exceeds the semaphore capacity of %d", n, s.size))
+	}
+
+	s.mu.Lock()
+	if s.size-s.cur >= n && s.waiters.Len() == 0 {
+		s.cur += n
+		s.mu.Unlock()
+		return nil
+	}
+	w := &semWaiter{n: n, ready: make(chan struct{})}
+	elem := s.waiters.PushBack(w)
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		select {
+		case <-w.ready:
+			// acquired just as ctx was done: give it back
+			s.cur -= n
+			s.notify()
+		default:
+			isFront := s.waiters.Front() == elem
+			s.waiters.Remove(elem)
+			// our departure may unblock those queued behind us
+			if isFront {
+				s.notify()
+			}
+		}
+		s.mu.Unlock()
+		return WrErr(ctx.Err())
+	}
+}
+
+// TryAcquireN acquires a weight of n without blocking, reporting whether it
+// did. A weight below 1 is never acquired.
+func (s *Semaphore) TryAcquireN(n int) bool {
+	if n < 1 {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.size-s.cur >= n && s.waiters.Len() == 0 {
+		s.cur += n
+		return true
+	}
+	return false
+}
+
+// ReleaseN returns a weight of n, panicking when n is below 1 or when
+// releasing more than is held
+func (s *Semaphore) ReleaseN(n int) {
+	if n < 1 {
+		panic(fmt.Sprintf("cmn.Semaphore: released non-positive weight %d", n))
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cur -= n
+	if s.cur < 0 {
+		panic("cmn.Semaphore: released more than held")
+	}
+	s.notify()
+}
+
+// wakes up queued waiters in order, for as long as they fit
+func (s *Semaphore) notify() {
+	for {
+		front := s.waiters.Front()
+		if front == nil {
+			return
+		}
+		w := front.Value.(*semWaiter)
+		if s.size-s.cur < w.n {
+			return
+		}
+		s.cur += w.n
+		s.waiters.Remove(front)
+		close(w.ready)
+	}
+}
@@ -0,0 +1,142 @@
+package cmn
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSemaphoreLimit(t *testing.T) {
+	const size = 3
+	s := NewSemaphore(size)
+
+	var cur, peak atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.Acquire(context.Background()); err != nil {
+				t.Error(err)
+				return
+			}
+			defer s.Release()
+			n := cur.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			cur.Add(-1)
+		}()
+	}
+	wg.Wait()
+
+	if p := peak.Load(); p > size {
+		t.Fatalf("peak concurrency %d exceeds the capacity of %d", p, size)
+	}
+	if !s.TryAcquireN(size) {
+		t.Fatal("capacity not fully returned")
+	}
+}
+
+func TestSemaphoreCancel(t *testing.T) {
+	s := NewSemaphore(2)
+	if !s.TryAcquireN(2) {
+		t.Fatal("initial acquire failed")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := s.Acquire(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a deadline error, got %v", err)
+	}
+	if _, isFramed := err.(*cmnErr); !isFramed { //nolint:errorlint
+		t.Errorf("error not framed: %#v", err)
+	}
+
+	// nothing was acquired by the abandoned waiter
+	s.ReleaseN(2)
+	if !s.TryAcquireN(2) {
+		t.Fatal("cancelled Acquire retained some weight")
+	}
+	s.ReleaseN(2)
+
+	if err := s.AcquireN(context.Background(), 3); err == nil {
+		t.Error("acquiring more than the capacity must fail")
+	}
+}
+
+func TestSemaphoreFIFO(t *testing.T) {
+	s := NewSemaphore(3)
+	if !s.TryAcquireN(2) {
+		t.Fatal("initial acquire failed")
+	}
+
+	bigDone := make(chan struct{})
+	go func() {
+		defer close(bigDone)
+		if err := s.AcquireN(context.Background(), 3); err != nil {
+			t.Error(err)
+		}
+	}()
+	// wait for the large request to queue up
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+		s.mu.Lock()
+		queued := s.waiters.Len()
+		s.mu.Unlock()
+		if queued == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("large request never queued")
+		}
+	}
+
+	// a weight of 1 is available, but a queued waiter precedes us
+	if s.TryAcquireN(1) {
+		t.Fatal("a later small request overtook the queued large one")
+	}
+
+	s.ReleaseN(2)
+	<-bigDone
+	s.ReleaseN(3)
+	if !s.TryAcquireN(3) {
+		t.Fatal("capacity not fully returned")
+	}
+}
+
+func TestSemaphoreNonPositiveWeight(t *testing.T) {
+	s := NewSemaphore(2)
+
+	for _, n := range []int{0, -1} {
+		if err := s.AcquireN(context.Background(), n); err == nil {
+			t.Errorf("AcquireN(%d) unexpectedly succeeded", n)
+		}
+		if s.TryAcquireN(n) {
+			t.Errorf("TryAcquireN(%d) unexpectedly succeeded", n)
+		}
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("ReleaseN(%d) did not panic", n)
+				}
+			}()
+			s.ReleaseN(n)
+		}()
+	}
+
+	// none of the above may have altered the available capacity
+	if !s.TryAcquireN(2) {
+		t.Fatal("capacity altered by non-positive weights")
+	}
+	if s.TryAcquireN(1) {
+		t.Fatal("capacity grown by non-positive weights")
+	}
+}
@@ -0,0 +1,59 @@
+package cmn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// DefaultSignals is the set of signals SignalContext reacts to when none are
+// specified. On unix platforms SIGHUP and SIGPIPE are included as well.
+var DefaultSignals = []os.Signal{
+	syscall.SIGTERM,
+	os.Interrupt,
+}
+
+// SignalError is the context.Cause() of a context cancelled by SignalContext
+type SignalError struct {
+	Signal os.Signal
+}
+
+func (e *SignalError) Error() string { return fmt.Sprintf("received signal %s", e.Signal) }
+
+// SignalContext returns a context cancelled upon receipt of any of the given
+// signals ( DefaultSignals if none specified ), or when the returned cancel
+// function is called. Signal notification is stopped once the context is done,
+// after which a repeated signal gets its default action ( e.g. termination ).
+// Use ContextSignal to distinguish a signal-initiated cancellation.
+func SignalContext(parent context.Context, sigs ...os.Signal) (context.Context, context.CancelFunc) {
+	if len(sigs) == 0 {
+		sigs = DefaultSignals
+	}
+
+	ctx, cancel := context.WithCancelCause(parent)
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+	go func() {
+		defer signal.Stop(ch)
+		select {
+		case s := <-ch:
+			cancel(&SignalError{Signal: s})
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() { cancel(nil) }
+}
+
+// ContextSignal returns the signal that cancelled a context created by SignalContext, if any
+func ContextSignal(ctx context.Context) (os.Signal, bool) {
+	var se *SignalError
+	if errors.As(context.Cause(ctx), &se) {
+		return se.Signal, true
+	}
+	return nil, false
+}
@@ -0,0 +1,9 @@
+//go:build unix
+
+package cmn
+
+import "syscall"
+
+func init() {
+	DefaultSignals = append(DefaultSignals, syscall.SIGHUP, syscall.SIGPIPE)
+}
@@ -0,0 +1,66 @@
+package cmn
+
+import "sync"
+
+// Singleflight collapses concurrent calls for the same key into a single
+// execution, whose result every caller receives. Unlike Cache nothing is
+// retained: a call arriving after the execution completed starts a new one.
+// The zero value is ready for use.
+type Singleflight[K comparable, V any] struct {
+	mu    sync.Mutex
+	calls map[K]*flight[V]
+}
+
+type flight[V any] struct {
+	done    chan struct{}
+	val     V
+	err     error
+	waiters int
+}
+
+// Do invokes fn, unless an invocation for k is already in flight, in which
+// case it waits for and returns its result instead. shared reports whether
+// the result was handed to more than one caller. A panic in fn is returned as
+// an error ( to every caller ).
+func (sf *Singleflight[K, V]) Do(k K, fn func() (V, error)) (v V, err error, shared bool) { //nolint:revive
+	sf.mu.Lock()
+	if f, found := sf.calls[k]; found {
+		f.waiters++
+		sf.mu.Unlock()
+		<-f.done
+		return f.val, f.err, true
+	}
+	if sf.calls == nil {
+		sf.calls = make(map[K]*flight[V])
+	}
+	f := &flight[V]{done: make(chan struct{})}
+	sf.calls[k] = f
+	sf.mu.Unlock()
+
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				f.err = RecoverToErr(r)
+			}
+		}()
+		f.val, f.err = fn()
+	}()
+
+	sf.mu.Lock()
+	if sf.calls[k] == f {
+		delete(sf.calls, k)
+	}
+	shared = f.waiters > 0
+	close(f.done)
+	sf.mu.Unlock()
+
+	return f.val, f.err, shared
+}
+
+// Forget makes the next Do for k start a new execution, even while one is
+// still in flight: callers already waiting keep waiting for the old one
+func (sf *Singleflight[K, V]) Forget(k K) {
+	sf.mu.Lock()
+	delete(sf.calls, k)
+	sf.mu.Unlock()
+}
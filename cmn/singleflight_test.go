@@ -0,0 +1,83 @@
+package cmn
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSingleflightShared(t *testing.T) {
+	const callers = 16
+
+	var sf Singleflight[string, int]
+	var execs atomic.Int32
+	release := make(chan struct{})
+
+	type result struct {
+		v      int
+		err    error
+		shared bool
+	}
+	results := make(chan result, callers)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err, shared := sf.Do("k", func() (int, error) {
+				execs.Add(1)
+				<-release
+				return 42, nil
+			})
+			results <- result{v, err, shared}
+		}()
+	}
+
+	// wait for everyone to join the flight before letting it land
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		sf.mu.Lock()
+		var waiting int
+		if f := sf.calls["k"]; f != nil {
+			waiting = f.waiters
+		}
+		sf.mu.Unlock()
+		if waiting == callers-1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("only %d of %d callers joined the flight", waiting, callers-1)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+	close(results)
+
+	if n := execs.Load(); n != 1 {
+		t.Fatalf("fn executed %d times, expected 1", n)
+	}
+	for r := range results {
+		if r.v != 42 || r.err != nil || !r.shared {
+			t.Errorf("unexpected result %+v", r)
+		}
+	}
+
+	// nothing is retained
+	v, _, shared := sf.Do("k", func() (int, error) { execs.Add(1); return 7, nil })
+	if v != 7 || shared || execs.Load() != 2 {
+		t.Errorf("a call after completion did not start a new execution")
+	}
+}
+
+func TestSingleflightPanic(t *testing.T) {
+	var sf Singleflight[int, int]
+	_, err, _ := sf.Do(1, func() (int, error) { panic("boom") })
+	if err == nil || !strings.HasPrefix(err.Error(), "panic encountered: boom") {
+		t.Fatalf("panic not returned as an error: %v", err)
+	}
+	if v, err, _ := sf.Do(1, func() (int, error) { return 1, nil }); v != 1 || err != nil {
+		t.Errorf("key unusable after a panic: %v %v", v, err)
+	}
+}
@@ -0,0 +1,64 @@
+package cmn
+
+import (
+	"cmp"
+	"slices"
+)
+
+// GroupBy buckets the elements of s by the result of key, retaining their
+// relative order within each bucket
+func GroupBy[T any, K comparable](s []T, key func(T) K) map[K][]T {
+	ret := make(map[K][]T)
+	for _, e := range s {
+		k := key(e)
+		ret[k] = append(ret[k], e)
+	}
+	return ret
+}
+
+// CountBy returns how many elements of s share each result of key
+func CountBy[T any, K comparable](s []T, key func(T) K) map[K]int {
+	ret := make(map[K]int)
+	for _, e := range s {
+		ret[key(e)]++
+	}
+	return ret
+}
+
+// SortBy stable-sorts s in place in ascending order of the result of key,
+// which is invoked on every comparison and should thus be cheap
+func SortBy[T any, K cmp.Ordered](s []T, key func(T) K) {
+	slices.SortStableFunc(s, func(a, b T) int { return cmp.Compare(key(a), key(b)) })
+}
+
+// SortByDesc is SortBy in descending order, elements with equal keys still
+// retaining their relative order
+func SortByDesc[T any, K cmp.Ordered](s []T, key func(T) K) {
+	slices.SortStableFunc(s, func(a, b T) int { return cmp.Compare(key(b), key(a)) })
+}
+
+// Pair is an element of the result of Zip
+type Pair[A, B any] struct {
+	A A
+	B B
+}
+
+// Zip pairs up the elements of as and bs by index. On a length mismatch the
+// result is silently truncated to the shorter of the two, the excess being
+// ignored: compare the lengths beforehand where a mismatch is an error.
+func Zip[A, B any](as []A, bs []B) []Pair[A, B] {
+	ret := make([]Pair[A, B], min(len(as), len(bs)))
+	for i := range ret {
+		ret[i] = Pair[A, B]{A: as[i], B: bs[i]}
+	}
+	return ret
+}
+
+// Unzip is the inverse of Zip
+func Unzip[A, B any](ps []Pair[A, B]) ([]A, []B) {
+	as, bs := make([]A, len(ps)), make([]B, len(ps))
+	for i, p := range ps {
+		as[i], bs[i] = p.A, p.B
+	}
+	return as, bs
+}
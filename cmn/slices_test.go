@@ -0,0 +1,84 @@
+package cmn
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestZip(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		as   []int
+		bs   []string
+		exp  []Pair[int, string]
+	}{
+		{"equal", []int{1, 2}, []string{"a", "b"}, []Pair[int, string]{{1, "a"}, {2, "b"}}},
+		{"shorter bs", []int{1, 2, 3}, []string{"a"}, []Pair[int, string]{{1, "a"}}},
+		{"shorter as", []int{1}, []string{"a", "b", "c"}, []Pair[int, string]{{1, "a"}}},
+		{"empty bs", []int{1, 2}, nil, []Pair[int, string]{}},
+		{"both nil", nil, nil, []Pair[int, string]{}},
+	} {
+		got := Zip(tc.as, tc.bs)
+		if !slices.Equal(got, tc.exp) {
+			t.Errorf("%s: Zip(%v, %q) = %v, expected %v", tc.name, tc.as, tc.bs, got, tc.exp)
+		}
+		if got == nil {
+			t.Errorf("%s: Zip returned nil instead of an empty slice", tc.name)
+		}
+	}
+
+	as, bs := Unzip(Zip([]int{1, 2, 3}, []string{"a", "b"}))
+	if !slices.Equal(as, []int{1, 2}) || !slices.Equal(bs, []string{"a", "b"}) {
+		t.Errorf("Unzip of a truncated Zip returned %v %q", as, bs)
+	}
+}
+
+func TestGroupByCountBy(t *testing.T) {
+	words := []string{"apple", "avocado", "banana", "blueberry", "cherry", "apricot"}
+	first := func(s string) byte { return s[0] }
+
+	groups := GroupBy(words, first)
+	if len(groups) != 3 ||
+		!slices.Equal(groups['a'], []string{"apple", "avocado", "apricot"}) ||
+		!slices.Equal(groups['b'], []string{"banana", "blueberry"}) ||
+		!slices.Equal(groups['c'], []string{"cherry"}) {
+		t.Errorf("unexpected groups: %q", groups)
+	}
+
+	counts := CountBy(words, first)
+	if len(counts) != 3 || counts['a'] != 3 || counts['b'] != 2 || counts['c'] != 1 {
+		t.Errorf("unexpected counts: %v", counts)
+	}
+
+	if g, c := GroupBy(nil, first), CountBy(nil, first); g == nil || len(g) != 0 || c == nil || len(c) != 0 {
+		t.Errorf("empty input must yield empty non-nil maps: %v %v", g, c)
+	}
+}
+
+func TestSortByStable(t *testing.T) {
+	type rec struct {
+		key int
+		id  string
+	}
+	recs := []rec{{2, "a"}, {1, "b"}, {2, "c"}, {0, "d"}, {1, "e"}, {2, "f"}, {1, "g"}}
+	ids := func(rs []rec) string {
+		var s string
+		for _, r := range rs {
+			s += r.id
+		}
+		return s
+	}
+	key := func(r rec) int { return r.key }
+
+	asc := slices.Clone(recs)
+	SortBy(asc, key)
+	if got := ids(asc); got != "dbegacf" {
+		t.Errorf("SortBy not stable: got order %s, expected dbegacf", got)
+	}
+
+	desc := slices.Clone(recs)
+	SortByDesc(desc, key)
+	if got := ids(desc); got != "acfbegd" {
+		t.Errorf("SortByDesc not stable: got order %s, expected acfbegd", got)
+	}
+}
@@ -0,0 +1,86 @@
+package cmn
+
+import (
+	"math"
+	"slices"
+)
+
+// SortedFloats returns a sorted copy of vals, as expected by Percentile
+func SortedFloats(vals []float64) []float64 {
+	s := slices.Clone(vals)
+	slices.Sort(s)
+	return s
+}
+
+// Percentile returns the p-th ( 0 to 100 ) percentile of the already sorted
+// input, linearly interpolating between the closest ranks. Returns NaN for
+// an empty input or a NaN p, otherwise p is clamped to [0,100].
+func Percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 || math.IsNaN(p) {
+		return math.NaN()
+	}
+	p = math.Max(0, math.Min(100, p))
+
+	rank := p / 100 * float64(len(sorted)-1)
+	lo := int(math.Floor(rank))
+	hi := int(math.Ceil(rank))
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(rank-float64(lo))
+}
+
+// Mean returns the arithmetic mean of vals, or NaN for an empty input
+func Mean(vals []float64) float64 {
+	if len(vals) == 0 {
+		return math.NaN()
+	}
+	var sum float64
+	for _, v := range vals {
+		sum += v
+	}
+	return sum / float64(len(vals))
+}
+
+// StdDev returns the population standard deviation of vals, or NaN for an
+// empty input
+func StdDev(vals []float64) float64 {
+	if len(vals) == 0 {
+		return math.NaN()
+	}
+	m := Mean(vals)
+	var sq float64
+	for _, v := range vals {
+		sq += (v - m) * (v - m)
+	}
+	return math.Sqrt(sq / float64(len(vals)))
+}
+
+// Summary is a set of basic descriptive statistics, see Summarize
+type Summary struct {
+	Count  int     `json:"count"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"stddev"`
+	P50    float64 `json:"p50"`
+	P95    float64 `json:"p95"`
+	P99    float64 `json:"p99"`
+}
+
+// Summarize computes a Summary of vals, which need not be sorted. An empty
+// input yields the zero Summary ( rather than NaN fields, which would make it
+// impossible to JSON-marshal ): check Count before using the rest.
+func Summarize(vals []float64) Summary {
+	s := SortedFloats(vals)
+	if len(s) == 0 {
+		return Summary{}
+	}
+	return Summary{
+		Count:  len(s),
+		Min:    s[0],
+		Max:    s[len(s)-1],
+		Mean:   Mean(s),
+		StdDev: StdDev(s),
+		P50:    Percentile(s, 50),
+		P95:    Percentile(s, 95),
+		P99:    Percentile(s, 99),
+	}
+}
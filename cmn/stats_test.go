@@ -0,0 +1,85 @@
+package cmn
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func floatsEqual(a, b float64) bool {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return math.IsNaN(a) && math.IsNaN(b)
+	}
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := []float64{10, 20, 30, 40, 50}
+	for _, tc := range []struct {
+		in  []float64
+		p   float64
+		exp float64
+	}{
+		{sorted, 0, 10},
+		{sorted, 50, 30},
+		{sorted, 100, 50},
+		{sorted, 25, 20},
+		{sorted, 10, 14}, // interpolated between ranks 0 and 1
+		{sorted, 95, 48},
+		{sorted, -5, 10},  // clamped
+		{sorted, 250, 50}, // clamped
+		{sorted, math.Inf(1), 50},
+		{sorted, math.Inf(-1), 10},
+		{sorted, math.NaN(), math.NaN()},
+		{[]float64{7}, 99, 7},
+		{nil, 50, math.NaN()},
+	} {
+		if got := Percentile(tc.in, tc.p); !floatsEqual(got, tc.exp) {
+			t.Errorf("Percentile(%v, %v) = %v, expected %v", tc.in, tc.p, got, tc.exp)
+		}
+	}
+}
+
+func TestMeanStdDev(t *testing.T) {
+	for _, tc := range []struct {
+		in           []float64
+		mean, stddev float64
+	}{
+		{nil, math.NaN(), math.NaN()},
+		{[]float64{}, math.NaN(), math.NaN()},
+		{[]float64{5}, 5, 0},
+		{[]float64{2, 4, 4, 4, 5, 5, 7, 9}, 5, 2},
+		{[]float64{-1, 1}, 0, 1},
+	} {
+		if got := Mean(tc.in); !floatsEqual(got, tc.mean) {
+			t.Errorf("Mean(%v) = %v, expected %v", tc.in, got, tc.mean)
+		}
+		if got := StdDev(tc.in); !floatsEqual(got, tc.stddev) {
+			t.Errorf("StdDev(%v) = %v, expected %v", tc.in, got, tc.stddev)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	in := []float64{9, 2, 7, 4, 5, 4, 5, 4}
+	s := Summarize(in)
+	if s.Count != 8 || s.Min != 2 || s.Max != 9 || !floatsEqual(s.Mean, 5) || !floatsEqual(s.StdDev, 2) ||
+		!floatsEqual(s.P50, 4.5) || !floatsEqual(s.P95, 8.3) || !floatsEqual(s.P99, 8.86) {
+		t.Errorf("unexpected summary %+v", s)
+	}
+	if in[0] != 9 {
+		t.Error("input reordered")
+	}
+
+	empty := Summarize(nil)
+	if empty != (Summary{}) {
+		t.Errorf("summary of an empty input is not the zero Summary: %+v", empty)
+	}
+	j, err := json.Marshal(empty)
+	if err != nil {
+		t.Fatalf("summary of an empty input can not be marshalled: %s", err)
+	}
+	if string(j) != `{"count":0,"min":0,"max":0,"mean":0,"stddev":0,"p50":0,"p95":0,"p99":0}` {
+		t.Errorf("unexpected JSON %s", j)
+	}
+}
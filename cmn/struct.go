@@ -0,0 +1,206 @@
+package cmn
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// RedactedValue replaces the value of fields tagged `redact:"true"` in StructToMap
+const RedactedValue = "[REDACTED]"
+
+// StructToMap converts a struct ( or pointer to one ) into a map suitable for
+// structured logging. Keys are taken from the supplied struct tag ( `json` if
+// empty ), falling back to the field name; fields tagged "-" and unexported
+// fields are skipped. Nested structs become nested maps, pointers are followed
+// ( a nil pointer becomes nil ), and embedded structs without an explicit tag
+// name are inlined. Fields tagged `redact:"true"` have their value replaced by
+// RedactedValue. A value referencing itself ( through a pointer, map or slice )
+// is an error.
+func StructToMap(v interface{}, tag string) (map[string]interface{}, error) {
+	if tag == "" {
+		tag = "json"
+	}
+	w := &structWalker{tag: tag, path: make(refPath)}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, WrErr(fmt.Errorf("nil %T supplied", v))
+		}
+		if _, err := w.path.enter(rv); err != nil {
+			return nil, err
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, WrErr(fmt.Errorf("input type not a struct: %T", v))
+	}
+
+	ret := make(map[string]interface{}, rv.NumField())
+	if err := w.into(ret, rv); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// a reference-type value: same address and type means same value
+type refID struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+// the references currently being descended into: encountering one again is a
+// cycle, while the same value merely appearing repeatedly elsewhere is fine
+type refPath map[refID]struct{}
+
+func (p refPath) enter(rv reflect.Value) (leave func(), err error) {
+	id := refID{ptr: rv.Pointer(), typ: rv.Type()}
+	if _, isCycle := p[id]; isCycle {
+		return nil, WrErr(fmt.Errorf("cycle detected: %s references itself", rv.Type()))
+	}
+	p[id] = struct{}{}
+	return func() { delete(p, id) }, nil
+}
+
+type structWalker struct {
+	tag  string
+	path refPath
+}
+
+func (w *structWalker) into(dst map[string]interface{}, rv reflect.Value) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+
+		name, _, _ := strings.Cut(sf.Tag.Get(w.tag), ",")
+		if name == "-" {
+			continue
+		}
+
+		fv := rv.Field(i)
+
+		if sf.Anonymous && name == "" {
+			inlined, err := w.inline(dst, fv)
+			if err != nil {
+				return err
+			}
+			if inlined {
+				continue
+			}
+		}
+
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+
+		if sf.Tag.Get("redact") == "true" {
+			dst[name] = RedactedValue
+			continue
+		}
+
+		v, err := w.value(fv)
+		if err != nil {
+			return err
+		}
+		dst[name] = v
+	}
+	return nil
+}
+
+// inlines an embedded struct, reporting whether fv was one
+func (w *structWalker) inline(dst map[string]interface{}, fv reflect.Value) (bool, error) {
+	if fv.Kind() == reflect.Ptr && !fv.IsNil() {
+		leave, err := w.path.enter(fv)
+		if err != nil {
+			return false, err
+		}
+		defer leave()
+		return w.inline(dst, fv.Elem())
+	}
+	if fv.Kind() != reflect.Struct {
+		return false, nil
+	}
+	return true, w.into(dst, fv)
+}
+
+func (w *structWalker) value(rv reflect.Value) (interface{}, error) {
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		if rv.Kind() == reflect.Ptr {
+			leave, err := w.path.enter(rv)
+			if err != nil {
+				return nil, err
+			}
+			defer leave()
+		}
+		return w.value(rv.Elem())
+	case reflect.Struct:
+		// things like time.Time are leaves, not containers
+		if rv.CanInterface() {
+			switch rv.Interface().(type) {
+			case encoding.TextMarshaler, fmt.Stringer:
+				return rv.Interface(), nil
+			}
+		}
+		m := make(map[string]interface{}, rv.NumField())
+		if err := w.into(m, rv); err != nil {
+			return nil, err
+		}
+		return m, nil
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return nil, nil
+		}
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return rv.Interface(), nil
+		}
+		if rv.Kind() == reflect.Slice && rv.Len() > 0 {
+			leave, err := w.path.enter(rv)
+			if err != nil {
+				return nil, err
+			}
+			defer leave()
+		}
+		s := make([]interface{}, rv.Len())
+		for i := range s {
+			v, err := w.value(rv.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			s[i] = v
+		}
+		return s, nil
+	case reflect.Map:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		leave, err := w.path.enter(rv)
+		if err != nil {
+			return nil, err
+		}
+		defer leave()
+		m := make(map[string]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			v, err := w.value(iter.Value())
+			if err != nil {
+				return nil, err
+			}
+			m[fmt.Sprint(iter.Key().Interface())] = v
+		}
+		return m, nil
+	default:
+		if !rv.CanInterface() {
+			return nil, nil
+		}
+		return rv.Interface(), nil
+	}
+}
@@ -0,0 +1,76 @@
+package cmn
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type structNode struct {
+	Name string      `json:"name"`
+	Next *structNode `json:"next"`
+	Any  interface{} `json:"any"`
+}
+
+type structEmbedsSelf struct {
+	*structEmbedsSelf
+	Name string
+}
+
+func TestStructToMap(t *testing.T) {
+	shared := &structNode{Name: "leaf"}
+	m, err := StructToMap(&structNode{
+		Name: "root",
+		Next: shared,
+		Any:  []interface{}{shared, shared},
+	}, "")
+	if err != nil {
+		t.Fatalf("unexpected error for a repeated but acyclic pointer: %s", err)
+	}
+	leaf := map[string]interface{}{"name": "leaf", "next": nil, "any": nil}
+	if exp := map[string]interface{}{
+		"name": "root",
+		"next": leaf,
+		"any":  []interface{}{leaf, leaf},
+	}; !reflect.DeepEqual(m, exp) {
+		t.Errorf("got %#v, expected %#v", m, exp)
+	}
+}
+
+func TestStructToMapCycle(t *testing.T) {
+	selfPtr := &structNode{Name: "self"}
+	selfPtr.Next = selfPtr
+
+	indirect := &structNode{Name: "a", Next: &structNode{Name: "b"}}
+	indirect.Next.Next = indirect
+
+	selfMap := map[string]interface{}{}
+	selfMap["me"] = selfMap
+
+	selfSlice := []interface{}{nil}
+	selfSlice[0] = selfSlice
+
+	embedded := &structEmbedsSelf{Name: "e"}
+	embedded.structEmbedsSelf = embedded
+
+	for _, tc := range []struct {
+		name string
+		v    interface{}
+	}{
+		{"pointer to itself", selfPtr},
+		{"pointer through another", indirect},
+		{"map containing itself", &structNode{Any: selfMap}},
+		{"slice containing itself", &structNode{Any: selfSlice}},
+		{"embedding itself", embedded},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := StructToMap(tc.v, "")
+			if err == nil {
+				t.Fatalf("expected a cycle error, got %#v", m)
+			}
+			if !strings.Contains(err.Error(), "cycle detected") {
+				t.Errorf("unexpected error: %s", err)
+			}
+		})
+	}
+}
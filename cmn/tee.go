@@ -0,0 +1,52 @@
+package cmn
+
+import "io"
+
+type teeWriter []io.Writer
+
+// TeeWriter returns a writer duplicating every write to all of ws. Unlike
+// io.MultiWriter, a failing destination does not stop the write from reaching
+// the remaining ones. The result of a write is len(p) and no error when every
+// destination accepted all of p, otherwise it is the byte count and framed
+// error of the first destination to fail, a short write being reported as
+// io.ErrShortWrite.
+func TeeWriter(ws ...io.Writer) io.Writer {
+	return teeWriter(append([]io.Writer(nil), ws...))
+}
+
+func (t teeWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	var firstErr error
+	for _, w := range t {
+		wn, err := w.Write(p)
+		if err == nil && wn != len(p) {
+			err = io.ErrShortWrite
+		}
+		if err != nil && firstErr == nil {
+			n, firstErr = wn, WrErr(err)
+		}
+	}
+	return n, firstErr
+}
+
+type teeReader struct {
+	r io.Reader
+	w io.Writer
+}
+
+// TeeReader returns a reader writing everything read from r to all of ws
+// ( as a TeeWriter ) before returning it. An error writing to any of ws is
+// returned from Read ( framed ), alongside the byte count actually read.
+func TeeReader(r io.Reader, ws ...io.Writer) io.Reader {
+	return &teeReader{r: r, w: TeeWriter(ws...)}
+}
+
+func (t *teeReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if n > 0 {
+		if _, wErr := t.w.Write(p[:n]); wErr != nil {
+			return n, wErr
+		}
+	}
+	return n, err
+}
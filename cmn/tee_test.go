@@ -0,0 +1,73 @@
+package cmn
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// accepts at most n bytes per write, optionally failing
+type limitedWriter struct {
+	bytes.Buffer
+	n   int
+	err error
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		p = p[:w.n]
+	}
+	w.Buffer.Write(p)
+	return len(p), w.err
+}
+
+func TestTeeWriterShortWrite(t *testing.T) {
+	var full1, full2 bytes.Buffer
+	short := &limitedWriter{n: 3}
+
+	n, err := TeeWriter(&full1, short, &full2).Write([]byte("hello"))
+	if n != 3 || !errors.Is(err, io.ErrShortWrite) {
+		t.Fatalf("expected 3 bytes and io.ErrShortWrite, got %d, %v", n, err)
+	}
+	if _, isFramed := err.(*cmnErr); !isFramed { //nolint:errorlint
+		t.Errorf("error not framed: %#v", err)
+	}
+	// the failure does not stop the write from reaching the remaining destinations
+	if full1.String() != "hello" || full2.String() != "hello" || short.String() != "hel" {
+		t.Errorf("unexpected contents: %q %q %q", full1.String(), short.String(), full2.String())
+	}
+}
+
+func TestTeeWriterFirstError(t *testing.T) {
+	errA, errB := errors.New("a"), errors.New("b")
+	var ok bytes.Buffer
+
+	n, err := TeeWriter(&ok, &limitedWriter{n: 2, err: errA}, &limitedWriter{n: 1, err: errB}).Write([]byte("data"))
+	if n != 2 || !errors.Is(err, errA) || errors.Is(err, errB) {
+		t.Fatalf("expected the count and error of the first failure, got %d, %v", n, err)
+	}
+	if ok.String() != "data" {
+		t.Errorf("unexpected contents of the healthy destination: %q", ok.String())
+	}
+
+	n, err = TeeWriter(&ok, &ok).Write([]byte("xy"))
+	if n != 2 || err != nil {
+		t.Errorf("expected a complete write, got %d, %v", n, err)
+	}
+}
+
+func TestTeeReader(t *testing.T) {
+	var copy1 bytes.Buffer
+	out, err := io.ReadAll(TeeReader(strings.NewReader("some input"), &copy1))
+	if err != nil || string(out) != "some input" || copy1.String() != "some input" {
+		t.Fatalf("unexpected result: %q %q %v", out, copy1.String(), err)
+	}
+
+	buf := make([]byte, 8)
+	n, err := TeeReader(strings.NewReader("some input"), &limitedWriter{n: 2}).Read(buf)
+	if n != 8 || !errors.Is(err, io.ErrShortWrite) {
+		t.Fatalf("expected the read count alongside the short write, got %d, %v", n, err)
+	}
+}
@@ -0,0 +1,32 @@
+package cmn
+
+import (
+	"bytes"
+	"strings"
+)
+
+const utf8BOM = "\ufeff"
+
+// NormalizeNewlines converts every \r\n and lone \r line ending in s to \n
+func NormalizeNewlines(s string) string {
+	if !strings.Contains(s, "\r") {
+		return s
+	}
+	return strings.ReplaceAll(strings.ReplaceAll(s, "\r\n", "\n"), "\r", "\n")
+}
+
+// StripBOM returns b without its leading UTF-8 byte order mark, if any
+func StripBOM(b []byte) []byte {
+	return bytes.TrimPrefix(b, []byte(utf8BOM))
+}
+
+// TrimEachLine removes the leading and trailing whitespace of every line of
+// s, retaining the line structure. Line endings are normalized to \n first,
+// see NormalizeNewlines.
+func TrimEachLine(s string) string {
+	lines := strings.Split(NormalizeNewlines(s), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSpace(lines[i])
+	}
+	return strings.Join(lines, "\n")
+}
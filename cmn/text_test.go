@@ -0,0 +1,24 @@
+package cmn
+
+import "testing"
+
+func TestNormalizeNewlines(t *testing.T) {
+	for _, tc := range []struct{ in, out string }{
+		{"a\r\nb\rc\nd", "a\nb\nc\nd"},
+		{"\r\n\r\r\n\n", "\n\n\n\n"},
+		{"\n\r", "\n\n"},
+		{"trailing\r", "trailing\n"},
+		{"no newlines", "no newlines"},
+		{"", ""},
+	} {
+		if got := NormalizeNewlines(tc.in); got != tc.out {
+			t.Errorf("NormalizeNewlines(%q) = %q, expected %q", tc.in, got, tc.out)
+		}
+	}
+}
+
+func TestTrimEachLine(t *testing.T) {
+	if got := TrimEachLine("  a \r\n\tb\r c  \n"); got != "a\nb\nc\n" {
+		t.Errorf("unexpected result %q", got)
+	}
+}
@@ -0,0 +1,101 @@
+package cmn
+
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CompareVersions compares two semver-ish version strings, returning -1, 0 or
+// 1 when a is respectively lower than, equal to, or higher than b.
+//
+// Accepted format is `[v]MAJOR[.MINOR[.PATCH]][-PRERELEASE][+BUILD]`: the `v`
+// prefix is optional, missing MINOR/PATCH components are treated as 0, build
+// metadata is ignored, and prerelease precedence follows semver 2.0 ( a
+// prerelease sorts before the corresponding release ). Anything else,
+// including more than 3 numeric components, is an error.
+func CompareVersions(a, b string) (int, error) {
+	va, err := parseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	vb, err := parseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range va.nums {
+		if c := cmp.Compare(va.nums[i], vb.nums[i]); c != 0 {
+			return c, nil
+		}
+	}
+
+	switch {
+	case len(va.pre) == 0 && len(vb.pre) == 0:
+		return 0, nil
+	case len(va.pre) == 0:
+		return 1, nil
+	case len(vb.pre) == 0:
+		return -1, nil
+	}
+
+	for i := 0; i < len(va.pre) && i < len(vb.pre); i++ {
+		pa, pb := va.pre[i], vb.pre[i]
+		na, aErr := strconv.ParseUint(pa, 10, 64)
+		nb, bErr := strconv.ParseUint(pb, 10, 64)
+		switch {
+		case aErr == nil && bErr == nil:
+			if c := cmp.Compare(na, nb); c != 0 {
+				return c, nil
+			}
+		case aErr == nil: // numeric identifiers sort first
+			return -1, nil
+		case bErr == nil:
+			return 1, nil
+		default:
+			if c := strings.Compare(pa, pb); c != 0 {
+				return c, nil
+			}
+		}
+	}
+	return cmp.Compare(len(va.pre), len(vb.pre)), nil
+}
+
+type parsedVersion struct {
+	nums [3]uint64
+	pre  []string
+}
+
+func parseVersion(s string) (parsedVersion, error) {
+	var pv parsedVersion
+
+	v := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	v, _, _ = strings.Cut(v, "+")
+	v, pre, hasPre := strings.Cut(v, "-")
+	if hasPre {
+		if pre == "" {
+			return pv, WrErr(fmt.Errorf("version '%s' has an empty prerelease", s))
+		}
+		pv.pre = strings.Split(pre, ".")
+		for _, p := range pv.pre {
+			if p == "" {
+				return pv, WrErr(fmt.Errorf("version '%s' has an empty prerelease identifier", s))
+			}
+		}
+	}
+
+	parts := strings.Split(v, ".")
+	if len(parts) > 3 {
+		return pv, WrErr(fmt.Errorf("version '%s' has more than 3 numeric components", s))
+	}
+	for i, p := range parts {
+		n, err := strconv.ParseUint(p, 10, 64)
+		if err != nil {
+			return pv, WrErr(fmt.Errorf("version '%s' has an invalid numeric component '%s'", s, p))
+		}
+		pv.nums[i] = n
+	}
+
+	return pv, nil
+}
@@ -0,0 +1,52 @@
+package cmn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+)
+
+// WalkDirOptions tunes WalkDirWith
+type WalkDirOptions struct {
+	SkipPermissionErrors bool // silently skip unreadable files and directories, instead of aborting the walk
+}
+
+// WalkDir is WalkDirWith using the default options: any error, including
+// lack of permission to read a directory, aborts the walk.
+func WalkDir(ctx context.Context, root string, match func(path string, d fs.DirEntry) bool, fn func(path string) error) error {
+	return WalkDirWith(ctx, root, WalkDirOptions{}, match, fn)
+}
+
+// WalkDirWith walks the file tree rooted at root in lexical order ( as
+// filepath.WalkDir ), invoking fn for every non-directory entry for which
+// match ( when non-nil ) returns true. The walk is aborted upon cancellation
+// of ctx, or an error from fn, which is returned annotated with the path. fn
+// may return fs.SkipAll to stop the walk early without error.
+func WalkDirWith(ctx context.Context, root string, opts WalkDirOptions, match func(path string, d fs.DirEntry) bool, fn func(path string) error) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return WrErr(ctxErr)
+		}
+		if err != nil {
+			if opts.SkipPermissionErrors && errors.Is(err, fs.ErrPermission) {
+				if d != nil && d.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			return WrErr(err)
+		}
+		if d.IsDir() || (match != nil && !match(path, d)) {
+			return nil
+		}
+		if err := fn(path); err != nil {
+			if errors.Is(err, fs.SkipAll) {
+				return fs.SkipAll
+			}
+			return WrErr(fmt.Errorf("%s: %w", path, err))
+		}
+		return nil
+	})
+}
@@ -0,0 +1,65 @@
+package ufcli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ribasushi/go-toolbox/cmn"
+	"github.com/urfave/cli/v2"
+)
+
+// urfave resolves the command from the arguments left over after parsing the
+// global flags: skip over those ( and the values of the ones taking a value )
+// in order to find, and replace, an alias in the command position only.
+// Returns the ( possibly rewritten copy of the ) args and the alias replaced.
+func (uf *UFcli) rewriteCommandAlias(args []string, globalFlags []cli.Flag) ([]string, string) {
+	if len(uf.CommandAliases) == 0 {
+		return args, ""
+	}
+
+	takesValue := make(map[string]bool)
+	for _, f := range globalFlags {
+		df, isDocFlag := f.(cli.DocGenerationFlag)
+		for _, n := range f.Names() {
+			takesValue[n] = !isDocFlag || df.TakesValue()
+		}
+	}
+
+	for i := 1; i < len(args); i++ {
+		a := args[i]
+		if a == "--" {
+			return args, ""
+		}
+		if len(a) < 2 || a[0] != '-' {
+			canonical, isAlias := uf.CommandAliases[a]
+			if !isAlias {
+				return args, ""
+			}
+			ret := append([]string(nil), args...)
+			ret[i] = canonical
+			return ret, a
+		}
+		name := strings.TrimPrefix(strings.TrimPrefix(a, "-"), "-")
+		if !strings.Contains(name, "=") && takesValue[name] {
+			i++
+		}
+	}
+	return args, ""
+}
+
+// an alias must not shadow a command, nor point to a non-existent one
+func (uf *UFcli) validateCommandAliases(cmdNames map[string]string) error {
+	for _, alias := range cmn.SortedMapKeys(uf.CommandAliases) {
+		if _, clash := cmdNames[alias]; clash {
+			return cmn.WrErr(fmt.Errorf("command alias '%s' clashes with an already registered command or alias", alias))
+		}
+		if _, found := cmdNames[uf.CommandAliases[alias]]; !found {
+			return cmn.WrErr(fmt.Errorf("command alias '%s' points to the unknown command '%s'", alias, uf.CommandAliases[alias]))
+		}
+	}
+	return nil
+}
+
+func (uf *UFcli) warnCommandAlias(alias string) {
+	uf.GetLogger().Warnf("command '%s' is deprecated, use '%s' instead", alias, uf.CommandAliases[alias])
+}
@@ -0,0 +1,110 @@
+package ufcli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ribasushi/go-toolbox/cmn"
+)
+
+// the Command of an ErrorReportPath report on the --batch as a whole
+const batchReportCommand = "--batch"
+
+// the --batch as a whole, as opposed to its individual lines
+type batchIdentity struct {
+	id           string
+	started      time.Time
+	lineReported bool // the failure of a line was written to ErrorReportPath
+}
+
+type batchEntry struct {
+	lineNo int
+	args   []string
+}
+
+// reads `--batch` input: one invocation per line, blank lines and #-comments are skipped
+func readBatchEntries(path string) ([]batchEntry, error) {
+	var r io.Reader
+	if path == "-" {
+		r = os.Stdin
+	} else {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, cmn.WrErr(err)
+		}
+		defer f.Close() //nolint:errcheck
+		r = f
+	}
+
+	var entries []batchEntry
+	var lineNo int
+	if err := cmn.ScanLines(r, 0, func(l []byte) error {
+		lineNo++
+		line := strings.TrimSpace(string(l))
+		if line == "" || strings.HasPrefix(line, "#") {
+			return nil
+		}
+		args, err := splitBatchLine(line)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, batchEntry{lineNo: lineNo, args: args})
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("reading batch: %w", err)
+	}
+	return entries, nil
+}
+
+// minimal shell-like word splitting: whitespace separates, single quotes are
+// literal, double quotes and bare words honor backslash escapes
+func splitBatchLine(line string) ([]string, error) {
+	var (
+		args    []string
+		cur     strings.Builder
+		inWord  bool
+		quote   rune
+		escaped bool
+	)
+	for _, r := range line {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t':
+			if inWord {
+				args = append(args, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		default:
+			cur.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if escaped {
+		return nil, fmt.Errorf("trailing backslash")
+	}
+	if inWord {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,85 @@
+package ufcli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+)
+
+func TestBatchErrorReport(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		batch     string // file contents, none for a missing file
+		command   string
+		lineError bool
+	}{
+		{"failure followed by a success", "fail\nok\n", "fail", true},
+		{"success followed by a failure", "ok\nfail\nok\n", "fail", true},
+		{"batch-level failure", "", batchReportCommand, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			batchPath := filepath.Join(dir, "batch.txt")
+			if tc.batch != "" {
+				if err := os.WriteFile(batchPath, []byte(tc.batch), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			reportPath := filepath.Join(dir, "report.json")
+
+			if err := runHelperProcess(t, "TestBatchErrorReportHelper",
+				"UFCLI_TEST_BATCH="+batchPath,
+				"UFCLI_TEST_REPORT="+reportPath,
+			); err == nil {
+				t.Fatal("failed batch exited with success")
+			}
+
+			j, err := os.ReadFile(reportPath)
+			if err != nil {
+				t.Fatalf("report not written: %s", err)
+			}
+			var rep struct {
+				RunID   string `json:"run_id"`
+				Command string `json:"command"`
+				Error   string `json:"error"`
+			}
+			if err := json.Unmarshal(j, &rep); err != nil {
+				t.Fatal(err)
+			}
+			if rep.Command != tc.command {
+				t.Errorf("report blames command '%s', expected '%s'", rep.Command, tc.command)
+			}
+			if rep.RunID == "" {
+				t.Error("report lacks a run_id")
+			}
+			if tc.lineError && rep.Error != "induced failure" {
+				t.Errorf("report carries the error '%s' instead of that of the failed line", rep.Error)
+			}
+		})
+	}
+}
+
+func TestBatchErrorReportHelper(t *testing.T) {
+	batchPath := os.Getenv("UFCLI_TEST_BATCH")
+	if batchPath == "" {
+		t.Skip("subprocess of TestBatchErrorReport")
+	}
+	os.Args = []string{"ufclitest", "--batch", batchPath}
+	(&UFcli{
+		ErrorReportPath:     os.Getenv("UFCLI_TEST_REPORT"),
+		AllowConcurrentRuns: true,
+		BatchContinueOnErr:  true,
+		AppConfig: cli.App{
+			Name: "ufclitest",
+			Commands: []*cli.Command{
+				{Name: "ok", Action: func(*cli.Context) error { return nil }},
+				{Name: "fail", Action: func(*cli.Context) error { return errors.New("induced failure") }},
+			},
+		},
+	}).RunAndExit(context.Background())
+}
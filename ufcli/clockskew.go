@@ -0,0 +1,48 @@
+package ufcli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ribasushi/go-toolbox/cmn"
+)
+
+// ClockSkewCheck configures a comparison of the local clock against an NTP
+// server ahead of the first run. Failing to reach the server is only ever
+// warned about.
+type ClockSkewCheck struct {
+	NTPServer string        // host or host:port, defaults to pool.ntp.org
+	MaxSkew   time.Duration // the tolerated difference, defaults to 1 second
+	Fatal     bool          // if set, a larger difference fails the run instead of merely being warned about
+}
+
+func (uf *UFcli) checkClockSkew(ctx context.Context) error {
+	c := uf.ClockSkewCheck
+	if c == nil {
+		return nil
+	}
+	server, maxSkew := c.NTPServer, c.MaxSkew
+	if server == "" {
+		server = "pool.ntp.org"
+	}
+	if maxSkew <= 0 {
+		maxSkew = time.Second
+	}
+
+	offset, err := cmn.ClockOffset(ctx, server)
+	if err != nil {
+		uf.GetLogger().Warnf("unable to check the local clock for skew: %s", err)
+		return nil
+	}
+	if offset.Abs() <= maxSkew {
+		return nil
+	}
+
+	msg := fmt.Sprintf("local clock is off by %s according to %s, more than the tolerated %s", cmn.HumanDuration(offset), server, cmn.HumanDuration(maxSkew))
+	if c.Fatal {
+		return cmn.WrErr(fmt.Errorf("aborting run: %s", msg))
+	}
+	uf.GetLogger().Warn(msg)
+	return nil
+}
@@ -0,0 +1,80 @@
+package ufcli
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/ribasushi/go-toolbox/cmn"
+	"github.com/urfave/cli/v2"
+)
+
+var cpuFlags = []cli.Flag{
+	&cli.IntFlag{
+		Name:  "maxprocs",
+		Usage: "limit the run to `N` simultaneously executing OS threads ( GOMAXPROCS, overrides any built-in default )",
+	},
+	&cli.StringFlag{
+		Name:  "cpus",
+		Usage: "pin the process to the CPUs in `LIST`, e.g. 0-3,6 ( Linux only, overrides any built-in default )",
+	},
+}
+
+// applies MaxProcs/CPUAffinity ( or their flag overrides ), returning the
+// resulting GOMAXPROCS, or 0 when neither is in effect
+func (uf *UFcli) applyCPULimits(cctx *cli.Context) (int, error) {
+	maxProcs := uf.MaxProcs
+	if cctx.IsSet("maxprocs") {
+		maxProcs = cctx.Int("maxprocs")
+	}
+	cpus := uf.CPUAffinity
+	if cctx.IsSet("cpus") {
+		var err error
+		if cpus, err = parseCPUList(cctx.String("cpus")); err != nil {
+			return 0, err
+		}
+	}
+	if maxProcs <= 0 && len(cpus) == 0 {
+		return 0, nil
+	}
+
+	if len(cpus) > 0 {
+		if err := setCPUAffinity(cpus); err != nil {
+			return 0, err
+		}
+		// the runtime sizes GOMAXPROCS by the affinity at startup only
+		if maxProcs <= 0 {
+			maxProcs = len(cpus)
+		}
+	}
+	runtime.GOMAXPROCS(maxProcs)
+	return maxProcs, nil
+}
+
+// the taskset(1) -c format: comma-separated CPU numbers and ranges
+func parseCPUList(s string) ([]int, error) {
+	seen := make(map[int]struct{})
+	var ret []int
+	for _, item := range cmn.ParseList(s) {
+		lo, hi, isRange := strings.Cut(item, "-")
+		from, err := strconv.Atoi(lo)
+		to := from
+		if err == nil && isRange {
+			to, err = strconv.Atoi(hi)
+		}
+		if err != nil || from < 0 || to < from {
+			return nil, cmn.WrErr(fmt.Errorf("invalid CPU list item '%s'", item))
+		}
+		for c := from; c <= to; c++ {
+			if _, dup := seen[c]; !dup {
+				seen[c] = struct{}{}
+				ret = append(ret, c)
+			}
+		}
+	}
+	if len(ret) == 0 {
+		return nil, cmn.WrErr(fmt.Errorf("CPU list '%s' is empty", s))
+	}
+	return ret, nil
+}
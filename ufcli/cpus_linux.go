@@ -0,0 +1,36 @@
+//go:build linux
+
+package ufcli
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/ribasushi/go-toolbox/cmn"
+	"golang.org/x/sys/unix"
+)
+
+// sched_setaffinity(2) applies to a single thread: pin every one the runtime
+// started so far, those started later inherit it from their creator
+func setCPUAffinity(cpus []int) error {
+	var set unix.CPUSet
+	for _, c := range cpus {
+		set.Set(c)
+	}
+
+	tids, err := os.ReadDir("/proc/self/task")
+	if err != nil {
+		return cmn.WrErr(err)
+	}
+	for _, t := range tids {
+		tid, err := strconv.Atoi(t.Name())
+		if err != nil {
+			continue
+		}
+		if err := unix.SchedSetaffinity(tid, &set); err != nil {
+			return cmn.WrErr(fmt.Errorf("pinning thread %d to CPUs %v: %w", tid, cpus, err))
+		}
+	}
+	return nil
+}
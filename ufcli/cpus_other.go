@@ -0,0 +1,13 @@
+//go:build !linux
+
+package ufcli
+
+import (
+	"errors"
+
+	"github.com/ribasushi/go-toolbox/cmn"
+)
+
+func setCPUAffinity([]int) error {
+	return cmn.WrErr(errors.New("CPU affinity is not supported on this platform"))
+}
@@ -0,0 +1,56 @@
+package ufcli
+
+import (
+	"os"
+	"strings"
+
+	"github.com/ribasushi/go-toolbox/cmn"
+)
+
+// env var flags are resolved by urfave while parsing, before any Before()
+// runs: the env file has to be loaded ahead of RunContext(), which in turn
+// means finding --env-file in the raw arguments ourselves
+func (uf *UFcli) loadEnvFile(args []string) error {
+	path := uf.EnvFile
+	if v, found := rawFlagValue(args, "env-file"); found {
+		path = v
+	}
+	if path == "" {
+		return nil
+	}
+
+	vars, err := cmn.ReadEnvFile(path)
+	if err != nil {
+		return err
+	}
+	for _, k := range cmn.SortedMapKeys(vars) {
+		if _, isSet := os.LookupEnv(k); isSet && !uf.EnvFileOverride {
+			continue
+		}
+		if err := os.Setenv(k, vars[k]); err != nil {
+			return cmn.WrErr(err)
+		}
+	}
+	return nil
+}
+
+// finds the value of a string flag in --name=val / --name val form ( one or two dashes )
+func rawFlagValue(args []string, name string) (string, bool) {
+	for i := 1; i < len(args); i++ {
+		a := args[i]
+		if a == "--" {
+			break
+		}
+		if !strings.HasPrefix(a, "-") {
+			continue
+		}
+		a = strings.TrimPrefix(strings.TrimPrefix(a, "-"), "-")
+		if a == name && i+1 < len(args) {
+			return args[i+1], true
+		}
+		if v, found := strings.CutPrefix(a, name+"="); found {
+			return v, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,49 @@
+package ufcli
+
+import (
+	"encoding/json"
+	"net"
+	"time"
+)
+
+const eventSocketTimeout = 250 * time.Millisecond
+
+type lifecycleEvent struct {
+	Event   string    `json:"event"` // begin / finish / skip
+	Command string    `json:"command"`
+	RunID   string    `json:"run_id"`
+	TS      time.Time `json:"ts"`
+	Success *bool     `json:"success,omitempty"`
+	Outcome string    `json:"outcome,omitempty"`
+	Reason  string    `json:"reason,omitempty"`
+}
+
+// best-effort: a connection per event, so that a restarted listener is picked
+// up transparently, and a short deadline, so that a wedged one can not stall us
+func (uf *UFcli) sendEvent(ev lifecycleEvent) {
+	if uf.EventSocketPath == "" {
+		return
+	}
+	ev.TS = time.Now()
+
+	j, err := json.Marshal(ev)
+	if err == nil {
+		var c net.Conn
+		if c, err = net.DialTimeout("unix", uf.EventSocketPath, eventSocketTimeout); err == nil {
+			c.SetWriteDeadline(time.Now().Add(eventSocketTimeout)) //nolint:errcheck
+			_, err = c.Write(append(j, '\n'))
+			if cErr := c.Close(); err == nil {
+				err = cErr
+			}
+		}
+	}
+	if err != nil {
+		// an absent listener is not worth more than a single warning
+		if !uf.eventSocketWarned {
+			uf.eventSocketWarned = true
+			uf.GetLogger().Warnf("unable to deliver lifecycle event to '%s' ( further failures logged at DEBUG ): %s", uf.EventSocketPath, err)
+		} else {
+			uf.GetLogger().Debugf("unable to deliver lifecycle event to '%s': %s", uf.EventSocketPath, err)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package ufcli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ribasushi/go-toolbox/cmn"
+	"github.com/urfave/cli/v2"
+	"github.com/urfave/cli/v2/altsrc"
+)
+
+// LenientBoolFlag is a config-file-aware boolean flag accepting every
+// spelling cmn.ParseBool does ( yes/no, on/off, ... ), whether it comes from
+// the command line, the environment or the TOML file ( as either a native
+// boolean or a string ). Retrieve its value via LenientBool.
+type LenientBoolFlag struct {
+	*altsrc.StringFlag
+}
+
+var _ altsrc.FlagInputSourceExtension = &LenientBoolFlag{}
+
+// ConfLenientBoolFlag is the LenientBoolFlag counterpart of ConfStringFlag
+func ConfLenientBoolFlag(fl *cli.StringFlag) *LenientBoolFlag {
+	return &LenientBoolFlag{StringFlag: altsrc.NewStringFlag(fl)}
+}
+
+// ApplyInputSourceValue implements altsrc.FlagInputSourceExtension
+func (f *LenientBoolFlag) ApplyInputSourceValue(cctx *cli.Context, isc altsrc.InputSourceContext) error {
+	err := f.StringFlag.ApplyInputSourceValue(cctx, isc)
+	if err == nil {
+		return nil
+	}
+
+	// not a string: perhaps a native TOML boolean, under any of the names
+	// ( a name absent from the file reads as an empty string without error )
+	for _, n := range f.Names() {
+		if _, sErr := isc.String(n); sErr == nil {
+			continue
+		}
+		b, bErr := isc.Bool(n)
+		if bErr != nil {
+			return err // report the original mismatch
+		}
+		for _, name := range f.Names() {
+			if sErr := cctx.Set(name, strconv.FormatBool(b)); sErr != nil {
+				return cmn.WrErr(sErr)
+			}
+		}
+		return nil
+	}
+	return err
+}
+
+// LenientBool returns the value of a LenientBoolFlag, unset/empty being false
+func LenientBool(cctx *cli.Context, name string) (bool, error) {
+	return cmn.ParseBoolOr(cctx.String(name), false)
+}
+
+// StringListFlag is a config-file-aware flag holding a list in the format
+// of cmn.ParseList ( e.g. `a,b c` ), whether it comes from the command line,
+// the environment or the TOML file ( as either a string or a native array ).
+// Retrieve its value via StringList.
+type StringListFlag struct {
+	*altsrc.StringFlag
+}
+
+var _ altsrc.FlagInputSourceExtension = &StringListFlag{}
+
+// ConfStringListFlag is the StringListFlag counterpart of ConfStringFlag
+func ConfStringListFlag(fl *cli.StringFlag) *StringListFlag {
+	return &StringListFlag{StringFlag: altsrc.NewStringFlag(fl)}
+}
+
+// ApplyInputSourceValue implements altsrc.FlagInputSourceExtension
+func (f *StringListFlag) ApplyInputSourceValue(cctx *cli.Context, isc altsrc.InputSourceContext) error {
+	err := f.StringFlag.ApplyInputSourceValue(cctx, isc)
+	if err == nil {
+		return nil
+	}
+
+	// not a string: perhaps a native TOML array
+	for _, n := range f.Names() {
+		if cctx.IsSet(n) {
+			return nil
+		}
+		ss, sErr := isc.StringSlice(n)
+		if sErr != nil {
+			return err // report the original mismatch
+		}
+		if len(ss) > 0 {
+			for _, name := range f.Names() {
+				if sErr := cctx.Set(name, strings.Join(ss, ",")); sErr != nil {
+					return cmn.WrErr(sErr)
+				}
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// StringList returns the items of a StringListFlag, see cmn.ParseList
+func StringList(cctx *cli.Context, name string) []string {
+	return cmn.ParseList(cctx.String(name))
+}
+
+// RequireExactlyOne returns a framed error unless exactly one of the named
+// flags is set, be it on the command line, in the environment or in the
+// TOML file. The error names the flags either missing or conflicting.
+func RequireExactlyOne(cctx *cli.Context, names ...string) error {
+	var set []string
+	for _, n := range names {
+		if cctx.IsSet(n) {
+			set = append(set, "--"+n)
+		}
+	}
+	if len(set) == 1 {
+		return nil
+	}
+
+	all := make([]string, len(names))
+	for i, n := range names {
+		all[i] = "--" + n
+	}
+	if len(set) == 0 {
+		return cmn.WrErr(fmt.Errorf("exactly one of %s is required, none was set", strings.Join(all, ", ")))
+	}
+	return cmn.WrErr(fmt.Errorf("exactly one of %s is allowed, conflicting flags set: %s", strings.Join(all, ", "), strings.Join(set, ", ")))
+}
+
+// ConfTimeFlag is the ConfStringFlag counterpart for timestamps in any of the
+// formats cmn.ParseTime accepts ( e.g. for --since/--until windows ). Unless
+// the flag already has an Action, an unparseable value is rejected as soon as
+// the flag is resolved. Retrieve its value via TimeValue.
+func ConfTimeFlag(fl *cli.StringFlag) *altsrc.StringFlag {
+	if fl.Action == nil {
+		fl.Action = func(_ *cli.Context, v string) error {
+			_, err := cmn.ParseTime(v)
+			return err
+		}
+	}
+	return altsrc.NewStringFlag(fl)
+}
+
+// TimeValue returns the value of a ConfTimeFlag, see cmn.ParseTime. An unset
+// flag yields a zero time.Time and no error.
+func TimeValue(cctx *cli.Context, name string) (time.Time, error) {
+	v := cctx.String(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	return cmn.ParseTime(v)
+}
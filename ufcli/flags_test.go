@@ -0,0 +1,56 @@
+package ufcli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+	"github.com/urfave/cli/v2/altsrc"
+)
+
+func TestLenientBoolFlagFromTOML(t *testing.T) {
+	for _, tc := range []struct {
+		toml string
+		exp  bool
+		err  bool
+	}{
+		{toml: "", exp: true}, // the default
+		{toml: "enabled = false", exp: false},
+		{toml: "enabled = true", exp: true},
+		{toml: `enabled = "no"`, exp: false},
+		{toml: `enabled = "on"`, exp: true},
+		{toml: "en = false", exp: false}, // an alias
+		{toml: `en = "off"`, exp: false},
+		{toml: "enabled = 3", err: true},
+	} {
+		path := filepath.Join(t.TempDir(), "conf.toml")
+		if err := os.WriteFile(path, []byte(tc.toml+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		flags := []cli.Flag{
+			ConfLenientBoolFlag(&cli.StringFlag{Name: "enabled", Aliases: []string{"en"}, Value: "true"}),
+		}
+		var got bool
+		app := &cli.App{
+			Flags:  flags,
+			Before: altsrc.InitInputSourceWithContext(flags, altsrc.NewTomlSourceFromFlagFunc("config")),
+			Action: func(cctx *cli.Context) (err error) {
+				got, err = LenientBool(cctx, "enabled")
+				return err
+			},
+		}
+		app.Flags = append(app.Flags, &cli.StringFlag{Name: "config"})
+
+		err := app.Run([]string{"app", "--config", path})
+		switch {
+		case tc.err && err == nil:
+			t.Errorf("%q: expected an error", tc.toml)
+		case !tc.err && err != nil:
+			t.Errorf("%q: unexpected error: %s", tc.toml, err)
+		case !tc.err && got != tc.exp:
+			t.Errorf("%q: got %t, expected %t", tc.toml, got, tc.exp)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package ufcli
+
+import (
+	"time"
+
+	"github.com/ribasushi/go-toolbox/cmn"
+	"github.com/urfave/cli/v2"
+)
+
+// RunSummary describes a finished run, as passed to OnSuccess/OnFailure
+type RunSummary struct {
+	Command      string
+	RunID        string
+	Outcome      string // success / failure / interrupted / timeout
+	Started      time.Time
+	Took         time.Duration
+	DryRun       bool
+	Processed    int64 // see SetProcessed, valid only when ProcessedSet
+	ProcessedSet bool
+	Fields       []interface{} // the key/value pairs of the FINISH log entry
+}
+
+// invokes OnSuccess or OnFailure, as appropriate, returning their error ( or panic )
+func (uf *UFcli) runOutcomeHook(cctx *cli.Context, sum RunSummary, runErr error) error {
+	var name string
+	var hook func() error
+	switch {
+	case runErr == nil && !cmn.IsNil(uf.OnSuccess):
+		name, hook = "OnSuccess", func() error { return uf.OnSuccess(cctx, sum) }
+	case runErr != nil && !cmn.IsNil(uf.OnFailure):
+		name, hook = "OnFailure", func() error { return uf.OnFailure(cctx, sum, runErr) }
+	default:
+		return nil
+	}
+
+	err := cmn.WithRecover(hook)()
+	if err != nil {
+		uf.GetLogger().Errorf("%s hook of '%s' run failed: %+v", name, sum.Command, err)
+	}
+	return err
+}
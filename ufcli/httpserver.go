@@ -0,0 +1,52 @@
+package ufcli
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/ribasushi/go-toolbox/cmn"
+)
+
+// binds synchronously, so that a bad address fails the run instead of being
+// merely logged, then serves until ctx is cancelled
+func (uf *UFcli) serveHTTP(ctx context.Context, g prometheus.Gatherer) error {
+	l, err := net.Listen("tcp", uf.HTTPAddr)
+	if err != nil {
+		return cmn.WrErr(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(g, promhttp.HandlerOpts{}))
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte("ok\n")) //nolint:errcheck
+	})
+
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	go func() {
+		if err := srv.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			uf.GetLogger().Errorf("http server on '%s' failed: %s", l.Addr(), err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		shCtx, shDone := context.WithTimeout(context.Background(), 5*time.Second)
+		defer shDone()
+		if err := srv.Shutdown(shCtx); err != nil {
+			uf.GetLogger().Warnf("http server on '%s' did not shut down cleanly: %s", l.Addr(), err)
+		}
+	}()
+
+	uf.GetLogger().Infof("serving /healthz and /metrics on http://%s", l.Addr())
+	return nil
+}
@@ -0,0 +1,111 @@
+package ufcli
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	fslock "github.com/ipfs/go-fs-lock"
+	"github.com/ribasushi/go-toolbox/cmn"
+)
+
+// Locker provides the mutual exclusion between concurrent runs of the same
+// command ( see AllowConcurrentRuns ). Lock must not block: a lock held by
+// someone else is reported as an error wrapping an fslock.LockedError, or one
+// recognized by UFcli.IsLockConflict, so that the quiet non-interactive
+// handling of conflicts applies.
+//
+// Reliability differs by implementation and filesystem:
+//   - FSLocker ( the default ) uses POSIX fcntl(2) record locks, and on Windows
+//     an exclusively-opened delete-on-close file. fcntl locks are honored over
+//     NFS only where the lock manager ( NLM, or NFSv4 built-in locking ) is
+//     operational, and are silently dropped if the process closes *any*
+//     descriptor of the lock file.
+//   - FlockLocker uses flock(2), and on Windows LockFileEx. flock locks are
+//     tied to the open file, thus immune to the above close() pitfall, but on
+//     NFS they are either emulated via fcntl ( Linux ) or entirely local to
+//     the client ( some BSDs ), the latter providing no cross-host exclusion.
+//
+// Neither is suitable across hosts without a shared, lock-capable filesystem.
+type Locker interface {
+	Lock(key string) (io.Closer, error)
+}
+
+// FSLocker is the default Locker, based on github.com/ipfs/go-fs-lock
+type FSLocker struct {
+	Dir  string      // directory of the lock files, defaults to os.TempDir()
+	Perm os.FileMode // if set, the exact mode of the lock file
+}
+
+// Lock implements Locker
+func (l *FSLocker) Lock(key string) (io.Closer, error) {
+	dir := l.Dir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	if l.Perm != 0 {
+		if err := precreateWithPerm(filepath.Join(dir, key), l.Perm); err != nil {
+			return nil, cmn.WrErr(err)
+		}
+	}
+	return fslock.Lock(dir, key) // no xerrors wrap on purpose
+}
+
+// FlockLocker is a Locker based on flock(2) ( LockFileEx on Windows ). The
+// lock files are left in place after release: removing them would race with
+// a concurrent locker.
+type FlockLocker struct {
+	Dir  string      // directory of the lock files, defaults to os.TempDir()
+	Perm os.FileMode // if set, the exact mode of the lock file, otherwise 0644 subject to the process umask
+}
+
+// Lock implements Locker
+func (l *FlockLocker) Lock(key string) (io.Closer, error) {
+	dir := l.Dir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	path := filepath.Join(dir, key)
+
+	perm := l.Perm
+	if perm == 0 {
+		perm = 0644
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, perm)
+	if err != nil {
+		return nil, cmn.WrErr(err)
+	}
+	if l.Perm != 0 {
+		if err := f.Chmod(l.Perm); err != nil {
+			f.Close() //nolint:errcheck
+			return nil, cmn.WrErr(err)
+		}
+	}
+
+	if err := flockExclusive(f); err != nil {
+		f.Close() //nolint:errcheck
+		if errors.Is(err, errWouldBlock) {
+			return nil, &os.PathError{
+				Op:   "lock",
+				Path: path,
+				Err:  fslock.LockedError("someone else has the lock"),
+			}
+		}
+		return nil, cmn.WrErr(err)
+	}
+
+	// closing the descriptor releases the lock
+	return f, nil
+}
+
+// fslock creates its file with a umask-dependent mode: make sure it already
+// exists with the one we want ( fslock truncates, but retains the mode )
+func precreateWithPerm(path string, perm os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_RDONLY|os.O_CREATE, perm)
+	if err != nil {
+		return err
+	}
+	defer f.Close() //nolint:errcheck
+	return f.Chmod(perm)
+}
@@ -0,0 +1,14 @@
+//go:build !unix && !windows
+
+package ufcli
+
+import (
+	"errors"
+	"os"
+)
+
+var errWouldBlock = errors.New("lock held elsewhere")
+
+func flockExclusive(*os.File) error {
+	return errors.New("FlockLocker is not supported on this platform")
+}
@@ -0,0 +1,124 @@
+package ufcli
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	fslock "github.com/ipfs/go-fs-lock"
+	"github.com/urfave/cli/v2"
+)
+
+func TestFlockLockerExclusion(t *testing.T) {
+	l := &FlockLocker{Dir: t.TempDir()}
+
+	held, err := l.Lock("test.lock")
+	if err != nil {
+		t.Fatalf("initial lock failed: %s", err)
+	}
+
+	if _, err := l.Lock("test.lock"); !errors.As(err, new(fslock.LockedError)) {
+		t.Fatalf("expected a LockedError while the lock is held, got: %v", err)
+	}
+
+	other, err := l.Lock("other.lock")
+	if err != nil {
+		t.Fatalf("lock of a different key failed: %s", err)
+	}
+	other.Close() //nolint:errcheck
+
+	if err := held.Close(); err != nil {
+		t.Fatalf("release failed: %s", err)
+	}
+	again, err := l.Lock("test.lock")
+	if err != nil {
+		t.Fatalf("lock after release failed: %s", err)
+	}
+	again.Close() //nolint:errcheck
+}
+
+func TestLockKeyFuncCommandFlags(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		args []string
+		env  []string
+		key  string
+	}{
+		{"flag", []string{"process", "--input", "a.csv"}, nil, "ufclitest-process-a_csv"},
+		{"alias", []string{"process", "-i=a.csv", "--verbose"}, nil, "ufclitest-process-a_csv_verbose"},
+		{"env", []string{"process"}, []string{"UFCLI_TEST_INPUT=b.csv"}, "ufclitest-process-b_csv"},
+		{"flag over env", []string{"process", "--input", "a.csv"}, []string{"UFCLI_TEST_INPUT=b.csv"}, "ufclitest-process-a_csv"},
+		{"default", []string{"process", "positional"}, nil, "ufclitest-process-default_csv"},
+		{"batch", []string{"--batch", "BATCH"}, nil, "ufclitest-process-c_csv"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			batchPath := filepath.Join(dir, "batch.txt")
+			if err := os.WriteFile(batchPath, []byte("process --input c.csv\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			args := strings.ReplaceAll(strings.Join(tc.args, " "), "BATCH", batchPath)
+			keyPath := filepath.Join(dir, "key")
+
+			if err := runHelperProcess(t, "TestLockKeyFuncCommandFlagsHelper", append([]string{
+				"UFCLI_TEST_LOCKKEY=" + keyPath,
+				"UFCLI_TEST_ARGS=" + args,
+			}, tc.env...)...); err != nil {
+				t.Fatalf("helper run failed: %s", err)
+			}
+
+			key, err := os.ReadFile(keyPath)
+			if err != nil {
+				t.Fatalf("no lock taken: %s", err)
+			}
+			if string(key) != tc.key {
+				t.Errorf("got lock key '%s', expected '%s'", key, tc.key)
+			}
+		})
+	}
+}
+
+type keyRecordingLocker struct{ path string }
+
+func (l keyRecordingLocker) Lock(key string) (io.Closer, error) {
+	return io.NopCloser(nil), os.WriteFile(l.path, []byte(key), 0644)
+}
+
+func TestLockKeyFuncCommandFlagsHelper(t *testing.T) {
+	keyPath := os.Getenv("UFCLI_TEST_LOCKKEY")
+	if keyPath == "" {
+		t.Skip("subprocess of TestLockKeyFuncCommandFlags")
+	}
+	os.Args = append([]string{"ufclitest"}, strings.Fields(os.Getenv("UFCLI_TEST_ARGS"))...)
+	(&UFcli{
+		Locker: keyRecordingLocker{path: keyPath},
+		LockKeyFunc: func(cctx *cli.Context) string {
+			k := cctx.String("input")
+			if cctx.Bool("verbose") {
+				k += "-verbose"
+			}
+			return k
+		},
+		AppConfig: cli.App{
+			Name: "ufclitest",
+			Commands: []*cli.Command{{
+				Name: "process",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "input", Aliases: []string{"i"}, Value: "default.csv", EnvVars: []string{"UFCLI_TEST_INPUT"}},
+					&cli.BoolFlag{Name: "verbose"},
+				},
+				Action: func(cctx *cli.Context) error {
+					// the command itself still sees its flags unaltered
+					if cctx.String("input") == "" {
+						return errors.New("input flag lost")
+					}
+					return nil
+				},
+			}},
+		},
+	}).RunAndExit(context.Background())
+}
@@ -0,0 +1,131 @@
+package ufcli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/prometheus/client_golang/prometheus"
+	prometheuspush "github.com/prometheus/client_golang/prometheus/push"
+	"github.com/ribasushi/go-toolbox/cmn"
+	"github.com/urfave/cli/v2"
+)
+
+// the per-run metrics, each named <MetricsName>_<command>_<suffix>
+type runMetric struct {
+	suffix string
+	help   string
+	note   string // circumstances of emission, for --list-metrics
+}
+
+var (
+	metricRunTime     = runMetric{suffix: "run_time", help: "How long did the job take (in milliseconds)"}
+	metricSuccess     = runMetric{suffix: "success", help: "Whether the job completed with success(1) or failure(0)"}
+	metricInterrupted = runMetric{suffix: "interrupted", help: "Whether the job was cut short by a termination signal(1) or ran to completion(0)"}
+	metricProcessed   = runMetric{suffix: "processed", help: "How many items the job reported as processed", note: "only when the command reports it"}
+	metricStage       = runMetric{suffix: "stage_<name>_seconds", help: "How long did a stage of the job take (in seconds)", note: "one per stage recorded via RecordStage"}
+
+	runMetrics = []runMetric{metricRunTime, metricSuccess, metricInterrupted, metricProcessed, metricStage}
+)
+
+// the distinct stages a single run may record, see RecordStage
+const maxStages = 32
+
+func stageMetric(name string) runMetric {
+	return runMetric{suffix: "stage_" + name + "_seconds", help: fmt.Sprintf("How long did stage %s of the job take (in seconds)", name)}
+}
+
+func (uf *UFcli) cmdFqName(cmd string) string {
+	return uf.metricStr(uf.metricsName() + "_" + cmd)
+}
+
+func (m runMetric) gauge(cmdFqName string, constLabels prometheus.Labels, val float64) prometheus.Gauge {
+	g := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name:        fmt.Sprintf("%s_%s", cmdFqName, m.suffix),
+		Help:        m.help,
+		ConstLabels: constLabels,
+	})
+	g.Set(val)
+	return g
+}
+
+// what --list-metrics prints
+func (uf *UFcli) listMetrics(w io.Writer, cmds []string) {
+	cmds = slices.Clone(cmds)
+	slices.Sort(cmds)
+	for _, c := range cmds {
+		for _, m := range runMetrics {
+			line := fmt.Sprintf("%s_%s\t%s", uf.cmdFqName(c), m.suffix, m.help)
+			if m.note != "" {
+				line += " ( " + m.note + " )"
+			}
+			fmt.Fprintln(w, line)
+		}
+	}
+}
+
+// the resolved prometheus_push_* flags
+type pushConf struct {
+	url      string
+	user     string
+	pass     string
+	instance string
+}
+
+// a pusher for the job of cmd, with the groupings and auth common to every push
+func (uf *UFcli) newPusher(pc pushConf, cmd string, flagLabels map[string]string) *prometheuspush.Pusher {
+	p := prometheuspush.New(pc.url, uf.metricStr(cmd))
+	if pc.instance != "" {
+		p = p.Grouping("instance", uf.metricStr(pc.instance))
+	}
+	for _, l := range cmn.SortedMapKeys(flagLabels) {
+		p = p.Grouping(l, flagLabels[l])
+	}
+	if uf.IncludeHostGrouping {
+		if h, err := os.Hostname(); err != nil {
+			uf.GetLogger().Warnf("unable to determine hostname, pushing metrics without a host grouping: %s", err)
+		} else {
+			p = p.Grouping("host", uf.metricStr(h))
+		}
+	}
+	if pc.user != "" {
+		p = p.BasicAuth(pc.user, pc.pass)
+	}
+	return p
+}
+
+// values longer than this are assumed to be unique-ish ( IDs, paths, hashes )
+// and are not turned into a grouping, in order to not flood the pushgateway
+const maxFlagLabelLen = 32
+
+// groupings the pusher sets on its own, or that a run may add
+var reservedGroupings = map[string]struct{}{"job": {}, "instance": {}, "host": {}, "success": {}, "dry_run": {}}
+
+// the MetricLabelFlags of cmd as pushgateway groupings: unset flags are
+// omitted, unsuitable ones are omitted with a warning
+func (uf *UFcli) flagLabels(cctx *cli.Context, cmd string) map[string]string {
+	names := uf.MetricLabelFlags[cmd]
+	if len(names) == 0 {
+		return nil
+	}
+
+	ret := make(map[string]string, len(names))
+	for _, n := range names {
+		if !cctx.IsSet(n) {
+			continue
+		}
+		l, v := uf.metricStr(n), cctx.String(n)
+		switch _, reserved := reservedGroupings[l]; {
+		case reserved:
+			uf.GetLogger().Warnf("not labeling metrics by flag '%s': '%s' is a reserved grouping", n, l)
+		case cmn.IsSecretKey(n):
+			uf.GetLogger().Warnf("not labeling metrics by flag '%s': its value looks like a secret", n)
+		case len(v) > maxFlagLabelLen:
+			uf.GetLogger().Warnf("not labeling metrics by flag '%s': its value is longer than %d characters", n, maxFlagLabelLen)
+		default:
+			ret[l] = uf.metricStr(v)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,149 @@
+package ufcli
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/expfmt"
+	"github.com/urfave/cli/v2"
+)
+
+func TestMetricsTextfile(t *testing.T) {
+	for _, tc := range []struct {
+		outcome     string
+		success     float64
+		interrupted float64
+	}{
+		{outcome: "success", success: 1},
+		{outcome: "failure", success: 0},
+		{outcome: "signal-during-action", success: 0, interrupted: 1},
+		{outcome: "signal-after-action", success: 1},
+	} {
+		t.Run(tc.outcome, func(t *testing.T) {
+			if runtime.GOOS == "windows" && strings.HasPrefix(tc.outcome, "signal") {
+				t.Skip("no SIGTERM on windows")
+			}
+			path := filepath.Join(t.TempDir(), "run.prom")
+
+			err := runHelperProcess(t, "TestMetricsTextfileHelper",
+				"UFCLI_TEST_TEXTFILE="+path,
+				"UFCLI_TEST_OUTCOME="+tc.outcome,
+			)
+			if tc.success == 1 && err != nil {
+				t.Fatalf("successful run exited with: %s", err)
+			}
+			if ee := (*exec.ExitError)(nil); tc.success == 0 && (!errors.As(err, &ee) || ee.ExitCode() == 0) {
+				t.Fatalf("failed run did not exit with a non-zero code: %v", err)
+			}
+
+			f, err := os.Open(path)
+			if err != nil {
+				t.Fatalf("textfile not written: %s", err)
+			}
+			defer f.Close() //nolint:errcheck
+			mfs, err := new(expfmt.TextParser).TextToMetricFamilies(f)
+			if err != nil {
+				t.Fatalf("unparseable textfile: %s", err)
+			}
+
+			gaugeOf := func(name string) float64 {
+				t.Helper()
+				mf, found := mfs[name]
+				if !found || len(mf.GetMetric()) != 1 {
+					t.Fatalf("metric '%s' missing from the textfile", name)
+				}
+				return mf.GetMetric()[0].GetGauge().GetValue()
+			}
+
+			if v := gaugeOf("ufclitest_Action_success"); v != tc.success {
+				t.Errorf("unexpected success value %v, expected %v", v, tc.success)
+			}
+			if v := gaugeOf("ufclitest_Action_run_time"); v < 50 {
+				t.Errorf("unexpected run_time value %vms, expected at least 50ms", v)
+			}
+			if v := gaugeOf("ufclitest_Action_interrupted"); v != tc.interrupted {
+				t.Errorf("unexpected interrupted value %v, expected %v", v, tc.interrupted)
+			}
+		})
+	}
+}
+
+func TestMetricsTextfileHelper(t *testing.T) {
+	path := os.Getenv("UFCLI_TEST_TEXTFILE")
+	if path == "" {
+		t.Skip("subprocess of TestMetricsTextfile")
+	}
+	outcome := os.Getenv("UFCLI_TEST_OUTCOME")
+
+	os.Args = []string{"ufclitest"}
+	(&UFcli{
+		MetricsTextfilePath: path,
+		AllowConcurrentRuns: true,
+		BeforeShutdown: func() error {
+			if outcome == "signal-after-action" {
+				sigterm()
+				time.Sleep(100 * time.Millisecond) // let the signal be handled
+			}
+			return nil
+		},
+		AppConfig: cli.App{
+			Name: "ufclitest",
+			Action: func(cctx *cli.Context) error {
+				time.Sleep(50 * time.Millisecond)
+				switch outcome {
+				case "failure":
+					return errors.New("induced failure")
+				case "signal-during-action":
+					sigterm()
+					<-cctx.Done()
+					return cctx.Err()
+				}
+				return nil
+			},
+		},
+	}).RunAndExit(context.Background())
+}
+
+func TestSanitizeMetricName(t *testing.T) {
+	for _, tc := range []struct {
+		in, out string
+	}{
+		{"sync", "sync"},
+		{"sync-v2", "sync_v2"},
+		{"sync.v2", "sync_v2"},
+		{"sync--v2", "sync_v2"},
+		{"sync_v2", "sync_v2"},
+		{"sync -. v2", "sync_v2"},
+		{"-sync-", "_sync_"},
+		{"2fa", "2fa"},
+		{"2-fa", "2_fa"},
+		{"123", "123"},
+		{"héllo", "h_llo"},
+		{"", ""},
+	} {
+		if got := SanitizeMetricName(tc.in); got != tc.out {
+			t.Errorf("SanitizeMetricName(%q): got %q, expected %q", tc.in, got, tc.out)
+		}
+	}
+
+	// distinct commands colliding on a single metric name
+	uf := &UFcli{AppConfig: cli.App{Name: "my-app"}}
+	if a, b := uf.cmdFqName("sync-v2"), uf.cmdFqName("sync.v2"); a != b || a != "my_app_sync_v2" {
+		t.Errorf("unexpected fully qualified names %q and %q", a, b)
+	}
+	if got := uf.cmdFqName("2fa"); got != "my_app_2fa" {
+		t.Errorf("a leading digit was not kept behind the prefix: got %q", got)
+	}
+
+	uf.MetricNameSanitizer = strings.ToUpper
+	if got := uf.cmdFqName("sync-v2"); got != "MY-APP_SYNC-V2" {
+		t.Errorf("custom MetricNameSanitizer not applied: got %q", got)
+	}
+}
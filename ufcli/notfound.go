@@ -0,0 +1,56 @@
+package ufcli
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+)
+
+// exit code of an unknown command, the same as urfave/cli uses for an unknown help topic
+const commandNotFoundExitCode = 3
+
+// SuggestCommand is the default UFcli.CommandNotFound hook: it reports the
+// unknown command on the app's ErrWriter, naming the closest registered
+// command or alias when there is a plausible one.
+func SuggestCommand(cctx *cli.Context, attempted string) {
+	msg := fmt.Sprintf("unknown command '%s'", attempted)
+	if s := closestCommand(cctx.App.VisibleCommands(), attempted); s != "" {
+		msg += fmt.Sprintf(", did you mean '%s'?", s)
+	}
+	fmt.Fprintln(cctx.App.ErrWriter, msg)
+}
+
+// closest by Levenshtein distance, and within a third of the length ( but at
+// least 2 edits ) in order to not suggest anything for complete nonsense
+func closestCommand(cmds []*cli.Command, attempted string) string {
+	best, bestDist := "", max(2, len(attempted)/3)+1
+	for _, c := range cmds {
+		for _, n := range c.Names() {
+			if d := levenshtein(attempted, n); d < bestDist {
+				best, bestDist = n, d
+			}
+		}
+	}
+	return best
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,128 @@
+package ufcli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ribasushi/go-toolbox/cmn"
+	"github.com/urfave/cli/v2"
+)
+
+var printConfigFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:  "print-config",
+		Usage: "print the effective global configuration with the source of every value in `FORMAT` ( table or json ) and exit",
+	},
+	&cli.BoolFlag{
+		Name:   "print-config-unredacted",
+		Usage:  "do not redact the values of hidden and secret-looking flags in the --print-config output",
+		Hidden: true,
+	},
+}
+
+type configEntry struct {
+	Name   string      `json:"name"`
+	Value  interface{} `json:"value"`
+	Source string      `json:"source"` // default / env / file / flag
+}
+
+// Only the global flags are covered: those of commands are not yet parsed
+// by the time the lifecycle starts. A value is attributed to the command
+// line or the environment when set before the config file was applied
+// ( i.e. it is in explicit ), and to the config file when set only after.
+func (uf *UFcli) printConfig(w io.Writer, cctx *cli.Context, explicit map[string]struct{}, args []string) error {
+	format := cctx.String("print-config")
+	if format != "table" && format != "json" {
+		return cmn.WrErr(fmt.Errorf("unsupported --print-config format '%s', expected 'table' or 'json'", format))
+	}
+	redact := !cctx.Bool("print-config-unredacted")
+
+	var entries []configEntry
+	for _, fl := range cctx.App.Flags {
+		name := fl.Names()[0]
+		if name == "help" || name == "version" || strings.HasPrefix(name, "print-config") {
+			continue
+		}
+
+		e := configEntry{Name: name, Value: configValue(cctx.Value(name)), Source: "default"}
+		if cctx.IsSet(name) {
+			e.Source = "file"
+			for _, n := range fl.Names() {
+				if _, isExplicit := explicit[n]; isExplicit {
+					e.Source = "env"
+					if argHasFlag(args, fl.Names()) {
+						e.Source = "flag"
+					}
+					break
+				}
+			}
+		}
+
+		// a boolean gives nothing away
+		if df, isDoc := fl.(cli.DocGenerationFlag); redact && (!isDoc || df.TakesValue()) {
+			vf, isVisibility := fl.(cli.VisibleFlag)
+			if s := fmt.Sprint(e.Value); s != "" && s != "[]" && (cmn.IsSecretKey(name) || (isVisibility && !vf.IsVisible())) {
+				e.Value = cmn.RedactedValue
+			}
+		}
+		entries = append(entries, e)
+	}
+	slices.SortFunc(entries, func(a, b configEntry) int { return strings.Compare(a.Name, b.Name) })
+
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return cmn.WrErr(enc.Encode(entries))
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "FLAG\tVALUE\tSOURCE")
+	for _, e := range entries {
+		fmt.Fprintf(tw, "%s\t%v\t%s\n", e.Name, e.Value, e.Source)
+	}
+	return cmn.WrErr(tw.Flush())
+}
+
+// unwraps the urfave slice types, and renders durations legibly
+func configValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case cli.StringSlice:
+		return x.Value()
+	case cli.IntSlice:
+		return x.Value()
+	case cli.Int64Slice:
+		return x.Value()
+	case cli.UintSlice:
+		return x.Value()
+	case cli.Uint64Slice:
+		return x.Value()
+	case cli.Float64Slice:
+		return x.Value()
+	case time.Duration:
+		return x.String()
+	default:
+		return v
+	}
+}
+
+// whether any of names appears as a flag in args, in any of the forms urfave accepts
+func argHasFlag(args []string, names []string) bool {
+	for _, a := range args[1:] {
+		if a == "--" {
+			break
+		}
+		if !strings.HasPrefix(a, "-") {
+			continue
+		}
+		a, _, _ = strings.Cut(strings.TrimPrefix(strings.TrimPrefix(a, "-"), "-"), "=")
+		if slices.Contains(names, a) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package ufcli
+
+import (
+	"bytes"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+
+	"github.com/ribasushi/go-toolbox/cmn"
+	"github.com/urfave/cli/v2"
+)
+
+var profileFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:  "cpuprofile",
+		Usage: "write a pprof CPU profile of the run to `FILE`",
+	},
+	&cli.StringFlag{
+		Name:  "memprofile",
+		Usage: "write a pprof heap profile taken at the end of the run to `FILE`",
+	},
+	&cli.StringFlag{
+		Name:  "trace",
+		Usage: "write a runtime execution trace of the run to `FILE`",
+	},
+}
+
+type profiler struct {
+	cpuPath, memPath, tracePath string
+	cpuBuf, traceBuf            bytes.Buffer
+}
+
+// returns nil when no profiling was requested
+func startProfiling(cctx *cli.Context) (*profiler, error) {
+	p := &profiler{
+		cpuPath:   cctx.String("cpuprofile"),
+		memPath:   cctx.String("memprofile"),
+		tracePath: cctx.String("trace"),
+	}
+	if p.cpuPath == "" && p.memPath == "" && p.tracePath == "" {
+		return nil, nil
+	}
+
+	// buffer in memory: the files are written atomically at the end
+	if p.cpuPath != "" {
+		if err := pprof.StartCPUProfile(&p.cpuBuf); err != nil {
+			return nil, cmn.WrErr(err)
+		}
+	}
+	if p.tracePath != "" {
+		if err := trace.Start(&p.traceBuf); err != nil {
+			if p.cpuPath != "" {
+				pprof.StopCPUProfile()
+			}
+			return nil, cmn.WrErr(err)
+		}
+	}
+	return p, nil
+}
+
+func (p *profiler) stop() error {
+	var firstErr error
+	keepErr := func(err error) {
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	if p.cpuPath != "" {
+		pprof.StopCPUProfile()
+		keepErr(cmn.AtomicWriteFile(p.cpuPath, p.cpuBuf.Bytes(), 0644))
+	}
+	if p.tracePath != "" {
+		trace.Stop()
+		keepErr(cmn.AtomicWriteFile(p.tracePath, p.traceBuf.Bytes(), 0644))
+	}
+	if p.memPath != "" {
+		runtime.GC() // up-to-date statistics
+		var buf bytes.Buffer
+		if err := pprof.WriteHeapProfile(&buf); err != nil {
+			keepErr(cmn.WrErr(err))
+		} else {
+			keepErr(cmn.AtomicWriteFile(p.memPath, buf.Bytes(), 0644))
+		}
+	}
+
+	return firstErr
+}
@@ -0,0 +1,112 @@
+package ufcli
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/ribasushi/go-toolbox/cmn"
+	"github.com/urfave/cli/v2"
+	"github.com/urfave/cli/v2/altsrc"
+)
+
+// names of the flags given on the command line or via the environment: these
+// always take precedence over the config file, including on reload
+func explicitFlags(cctx *cli.Context, flags []cli.Flag) map[string]struct{} {
+	ret := make(map[string]struct{})
+	for _, fl := range flags {
+		for _, n := range fl.Names() {
+			if cctx.IsSet(n) {
+				ret[n] = struct{}{}
+			}
+		}
+	}
+	return ret
+}
+
+// Only top-level, config-file-aware ( Conf* ) flags holding a single value are
+// reloadable. Slice flags are not ( their altsrc implementation does not
+// support re-application ), nor are command-level flags, nor anything the
+// lifecycle already acted upon ( command selection, --batch, etc ). A value
+// removed from the config file retains its previous setting.
+//
+// Returns the names of the flags that changed. Upon any error, including one
+// from OnConfigReload, the previous values are restored.
+func (uf *UFcli) reloadConfig(cctx *cli.Context, explicit map[string]struct{}) (changed []string, err error) {
+	isc, err := altsrc.NewTomlSourceFromFile(uf.TOMLPath)
+	if err != nil {
+		return nil, cmn.WrErr(err)
+	}
+
+	var reloadable []cli.Flag
+	for _, fl := range cctx.App.Flags {
+		switch fl.(type) {
+		case *altsrc.StringSliceFlag, *altsrc.IntSliceFlag, *altsrc.Int64SliceFlag, *altsrc.Float64SliceFlag:
+			continue
+		}
+		if _, isExt := fl.(altsrc.FlagInputSourceExtension); isExt {
+			reloadable = append(reloadable, fl)
+		}
+	}
+
+	previous := make(map[string]string)
+	for _, fl := range reloadable {
+		for _, n := range fl.Names() {
+			previous[n] = fmt.Sprint(cctx.Value(n))
+		}
+	}
+	defer func() {
+		if err != nil {
+			for n, v := range previous {
+				cctx.Set(n, v) //nolint:errcheck
+			}
+			changed = nil
+		}
+	}()
+
+	// Source via a scratch context, which makes altsrc disregard the fact that
+	// everything is already "set" in the live one. Explicit flags are marked
+	// as set in the scratch instead, so that they are skipped. Depending on
+	// the flag type, altsrc either writes to the live flagset directly, or
+	// into the scratch: the latter values are copied over below.
+	fs := flag.NewFlagSet("reload", flag.ContinueOnError)
+	for _, fl := range reloadable {
+		for _, n := range fl.Names() {
+			fs.String(n, "", "")
+			if _, isExplicit := explicit[n]; isExplicit {
+				fs.Set(n, previous[n]) //nolint:errcheck
+			}
+		}
+	}
+	scratch := cli.NewContext(cctx.App, fs, nil)
+	for _, fl := range reloadable {
+		if err = fl.(altsrc.FlagInputSourceExtension).ApplyInputSourceValue(scratch, isc); err != nil {
+			return nil, cmn.WrErr(err)
+		}
+	}
+	fs.Visit(func(f *flag.Flag) {
+		if _, isExplicit := explicit[f.Name]; isExplicit || err != nil {
+			return
+		}
+		if sErr := cctx.Set(f.Name, f.Value.String()); sErr != nil {
+			err = cmn.WrErr(fmt.Errorf("invalid value for '%s': %w", f.Name, sErr))
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, fl := range reloadable {
+		n := fl.Names()[0]
+		if fmt.Sprint(cctx.Value(n)) != previous[n] {
+			changed = append(changed, n)
+		}
+	}
+
+	if !cmn.IsNil(uf.OnConfigReload) {
+		if err = cmn.WithRecover(func() error { return uf.OnConfigReload(cctx) })(); err != nil {
+			return nil, err
+		}
+	}
+
+	return changed, nil
+}
@@ -0,0 +1,274 @@
+package ufcli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/ribasushi/go-toolbox/cmn"
+	"go.uber.org/zap"
+)
+
+// per-run state reachable from the context handed to actions
+type runState struct {
+	uf           *UFcli
+	processed    atomic.Int64
+	processedSet atomic.Bool
+	command      atomic.Pointer[string]
+	runID        atomic.Pointer[string]
+	flagLabels   atomic.Pointer[map[string]string] // see MetricLabelFlags
+	stagesMu     sync.Mutex
+	stages       map[string]time.Duration // see RecordStage
+	push         atomic.Pointer[pushConf] // nil when pushing is not configured or --no-metrics
+	background   cmn.WG                   // for the lifetime of the process, not reset
+	closersMu    sync.Mutex
+	closers      []io.Closer   // see CloseOnShutdown, not reset
+	dryRun       bool          // set once before any action runs
+	stopped      chan struct{} // closed upon a shutdown signal, see StopRequested
+	stopOnce     sync.Once
+}
+
+type runStateKey struct{}
+
+func withRunState(ctx context.Context, rs *runState) context.Context {
+	return context.WithValue(ctx, runStateKey{}, rs)
+}
+
+func getRunState(ctx context.Context) *runState {
+	rs, _ := ctx.Value(runStateKey{}).(*runState)
+	return rs
+}
+
+func newRunState(uf *UFcli) *runState {
+	return &runState{uf: uf, stopped: make(chan struct{})}
+}
+
+func (rs *runState) requestStop() {
+	rs.stopOnce.Do(func() { close(rs.stopped) })
+}
+
+func (rs *runState) stopRequested() bool {
+	select {
+	case <-rs.stopped:
+		return true
+	default:
+		return false
+	}
+}
+
+// reset at every BEGIN
+func (rs *runState) reset(cmd, runID string) {
+	rs.command.Store(&cmd)
+	rs.runID.Store(&runID)
+	rs.flagLabels.Store(nil)
+	rs.stagesMu.Lock()
+	rs.stages = nil
+	rs.stagesMu.Unlock()
+	rs.processed.Store(0)
+	rs.processedSet.Store(false)
+}
+
+// SetProcessed records how many items the current run processed, as shown in
+// the FINISH log and metrics, and as checked against UFcli.MinProcessed. It
+// is a no-op for a ctx not derived from one provided by UFcli.
+func SetProcessed(ctx context.Context, n int64) {
+	if rs := getRunState(ctx); rs != nil {
+		rs.processed.Store(n)
+		rs.processedSet.Store(true)
+	}
+}
+
+// AddProcessed is SetProcessed incrementing the current count, safe for
+// concurrent use
+func AddProcessed(ctx context.Context, n int64) {
+	if rs := getRunState(ctx); rs != nil {
+		rs.processed.Add(n)
+		rs.processedSet.Store(true)
+	}
+}
+
+// Background runs fn in a new goroutine tracked by UFcli: at shutdown, once
+// the top context is cancelled, it waits ( for at most CloserTimeout, when
+// set ) for all such goroutines to return before releasing the GlobalInit
+// resources, logging their errors ( and panics ). fn receives ctx, so it
+// is also cancelled when ctx is. For a ctx not derived from one provided by
+// UFcli, fn is merely started in an untracked goroutine.
+func Background(ctx context.Context, fn func(context.Context) error) {
+	rs := getRunState(ctx)
+	if rs == nil {
+		go fn(ctx) //nolint:errcheck
+		return
+	}
+	rs.background.Go(cmn.WithRecover(func() error { return fn(ctx) }))
+}
+
+// CloseOnShutdown registers c ( e.g. a cmn.LineBuffer, whose buffered lines
+// would otherwise be lost ) to be closed during shutdown, once the action and
+// any Background tasks returned, but before the GlobalInit resources are
+// released. Closers are invoked in reverse order of registration, subject to
+// CloserTimeout, with their errors logged. It is a no-op for a ctx not derived
+// from one provided by UFcli.
+func CloseOnShutdown(ctx context.Context, c io.Closer) {
+	if rs := getRunState(ctx); rs != nil && !cmn.IsNil(c) {
+		rs.closersMu.Lock()
+		rs.closers = append(rs.closers, c)
+		rs.closersMu.Unlock()
+	}
+}
+
+func (rs *runState) closeRegistered() error {
+	rs.closersMu.Lock()
+	closers := rs.closers
+	rs.closers = nil
+	rs.closersMu.Unlock()
+
+	var errs cmn.Errors
+	for i := len(closers) - 1; i >= 0; i-- {
+		if err := cmn.WithRecover(closers[i].Close)(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// DryRun reports whether --dry-run is in effect: the action is expected to
+// refrain from making any changes. Always false for a ctx not derived from one
+// provided by UFcli.
+func DryRun(ctx context.Context) bool {
+	rs := getRunState(ctx)
+	return rs != nil && rs.dryRun
+}
+
+// PushMetrics pushes collectors to the pushgateway configured via the
+// prometheus_push_* flags, using the same job ( the current command ),
+// instance/host/MetricLabelFlags groupings and credentials as the FINISH
+// metrics, and a dry_run grouping under --dry-run. It is meant for emitting
+// intermediate metrics from within a long-running action. The push is a POST,
+// replacing only same-named metrics within the group. Bear in mind the FINISH
+// push is a PUT, replacing the entire group, thus metrics pushed here do not
+// outlive the run unless the FINISH push is skipped. It is a no-op when
+// pushing is not configured ( or --no-metrics is in effect ), and returns an
+// error for a ctx not derived from one provided by UFcli.
+func PushMetrics(ctx context.Context, collectors ...prometheus.Collector) error {
+	rs := getRunState(ctx)
+	if rs == nil {
+		return cmn.WrErr(errors.New("PushMetrics() requires a context provided by UFcli"))
+	}
+	pc, cmd := rs.push.Load(), rs.command.Load()
+	if pc == nil || cmd == nil || len(collectors) == 0 {
+		return nil
+	}
+
+	var labels map[string]string
+	if l := rs.flagLabels.Load(); l != nil {
+		labels = *l
+	}
+	p := rs.uf.newPusher(*pc, *cmd, labels)
+	if rs.dryRun {
+		p = p.Grouping("dry_run", "true")
+	}
+	for _, c := range collectors {
+		p = p.Collector(c)
+	}
+	if err := p.AddContext(ctx); err != nil {
+		return cmn.WrErr(fmt.Errorf("push of prometheus metrics to '%s' failed: %w", pc.url, err))
+	}
+	return nil
+}
+
+var _ cmn.FieldLogger = Logger(nil)
+
+// LoggerFrom returns the UFcli Logger wrapped in a cmn.ContextLogger, which
+// includes the command and run_id of the current run in every entry, followed
+// by the fields accumulated in ctx via cmn.WithLogFields ( these may override
+// the former ). For a ctx not derived from one provided by UFcli only the
+// latter are included, on a logger named "ufcli".
+func LoggerFrom(ctx context.Context) cmn.FieldLogger {
+	rs := getRunState(ctx)
+	if rs == nil {
+		return cmn.ContextLogger(ctx, logging.Logger("ufcli").WithOptions(zap.AddCallerSkip(1)))
+	}
+
+	lctx := context.Background()
+	if cmd, runID := rs.command.Load(), rs.runID.Load(); cmd != nil && runID != nil {
+		lctx = cmn.WithLogFields(lctx, "command", *cmd, "run_id", *runID)
+	}
+	// skip the wrapper frame, reporting the caller of the log method instead
+	return cmn.ContextLogger(cmn.WithLogFields(lctx, cmn.LogFields(ctx)...), rs.uf.GetLogger().WithOptions(zap.AddCallerSkip(1)))
+}
+
+// StopRequested reports whether a shutdown signal was received. It is meant
+// for polling under GracefulActionStop, where the context of the action is
+// only cancelled after GracefulStopTimeout, but is equally valid otherwise.
+// Always false for a ctx not derived from one provided by UFcli.
+func StopRequested(ctx context.Context) bool {
+	rs := getRunState(ctx)
+	return rs != nil && rs.stopRequested()
+}
+
+// RecordStage starts timing a stage of the current run, returning the
+// function marking its end ( only the first call counts ). The duration is
+// pushed right away, as PushMetrics does, and is also included in the FINISH
+// metrics as <MetricsName>_<command>_stage_<name>_seconds. The name is
+// sanitized like command names are, recording the same stage again replaces
+// its duration, and at most 32 distinct stages are recorded per run ( further
+// ones are dropped with a warning ). It is a no-op for a ctx not derived from
+// one provided by UFcli.
+func RecordStage(ctx context.Context, name string) func() {
+	rs := getRunState(ctx)
+	if rs == nil {
+		return func() {}
+	}
+	start := time.Now()
+	var once sync.Once
+	return func() { once.Do(func() { rs.recordStage(ctx, name, time.Since(start)) }) }
+}
+
+func (rs *runState) recordStage(ctx context.Context, name string, took time.Duration) {
+	name = rs.uf.metricStr(name)
+
+	rs.stagesMu.Lock()
+	if _, known := rs.stages[name]; !known && len(rs.stages) >= maxStages {
+		rs.stagesMu.Unlock()
+		rs.uf.GetLogger().Warnf("not recording stage '%s': a run may record at most %d distinct stages", name, maxStages)
+		return
+	}
+	if rs.stages == nil {
+		rs.stages = make(map[string]time.Duration)
+	}
+	rs.stages[name] = took
+	rs.stagesMu.Unlock()
+
+	cmd := rs.command.Load()
+	if cmd == nil {
+		return
+	}
+	if err := PushMetrics(ctx, stageMetric(name).gauge(rs.uf.cmdFqName(*cmd), nil, took.Seconds())); err != nil {
+		rs.uf.GetLogger().Warnf("%+v", err)
+	}
+}
+
+type stageTime struct {
+	name string
+	took time.Duration
+}
+
+// sorted by name
+func (rs *runState) stageTimes() []stageTime {
+	rs.stagesMu.Lock()
+	defer rs.stagesMu.Unlock()
+	ret := make([]stageTime, 0, len(rs.stages))
+	for _, n := range cmn.SortedMapKeys(rs.stages) {
+		ret = append(ret, stageTime{name: n, took: rs.stages[n]})
+	}
+	return ret
+}
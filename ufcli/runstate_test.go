@@ -0,0 +1,75 @@
+package ufcli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/ribasushi/go-toolbox/cmn"
+	"github.com/urfave/cli/v2"
+)
+
+// RunAndExit terminates the process: tests re-execute the test binary, running
+// only the named helper test, which is skipped unless env is set
+func runHelperProcess(t *testing.T, helper string, env ...string) error {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], "-test.run=^"+helper+"$") //nolint:gosec
+	cmd.Env = append(os.Environ(), env...)
+	return cmd.Run()
+}
+
+// for helper processes, not available on windows
+func sigterm() {
+	p, err := os.FindProcess(os.Getpid())
+	if err == nil {
+		err = p.Signal(syscall.SIGTERM)
+	}
+	if err != nil {
+		panic(err)
+	}
+}
+
+func TestCloseOnShutdown(t *testing.T) {
+	path := t.TempDir() + "/out.log"
+	if err := runHelperProcess(t, "TestCloseOnShutdownHelper", "UFCLI_TEST_LINEBUFFER="+path); err != nil {
+		t.Fatalf("helper run failed: %s", err)
+	}
+	out, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "line 1\nline 2\npartial" {
+		t.Fatalf("buffered output lost on shutdown, got %q", out)
+	}
+}
+
+func TestCloseOnShutdownHelper(t *testing.T) {
+	path := os.Getenv("UFCLI_TEST_LINEBUFFER")
+	if path == "" {
+		t.Skip("subprocess of TestCloseOnShutdown")
+	}
+	os.Args = []string{"ufclitest"}
+	(&UFcli{
+		AllowConcurrentRuns: true,
+		AppConfig: cli.App{
+			Name: "ufclitest",
+			Action: func(cctx *cli.Context) error {
+				f, err := os.Create(path)
+				if err != nil {
+					return err
+				}
+				// registered first: closed last
+				CloseOnShutdown(cctx.Context, f)
+
+				lb := cmn.NewLineBuffer(f, time.Hour, 0)
+				CloseOnShutdown(cctx.Context, lb)
+				fmt.Fprint(lb, "line 1\nline 2\npartial")
+				return nil
+			},
+		},
+	}).RunAndExit(context.Background())
+}
@@ -0,0 +1,95 @@
+package ufcli
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	logging "github.com/ipfs/go-log/v2"
+)
+
+// SignalAction is the behavior UFcli exhibits upon receipt of a signal
+type SignalAction int
+
+//nolint:revive
+const (
+	SignalShutdown     SignalAction = iota + 1 // graceful shutdown: the default for every signal in HandleSignals
+	SignalIgnore                               // explicitly ignore the signal
+	SignalLogLevelUp                           // raise log verbosity one step ( up to DEBUG )
+	SignalLogLevelDown                         // lower log verbosity one step ( down to ERROR )
+	SignalReloadConfig                         // re-read TOMLPath before the next RepeatEvery iteration, see OnConfigReload
+)
+
+func (a SignalAction) String() string {
+	switch a {
+	case SignalShutdown:
+		return "shutdown"
+	case SignalIgnore:
+		return "ignore"
+	case SignalLogLevelUp:
+		return "loglevel-up"
+	case SignalLogLevelDown:
+		return "loglevel-down"
+	case SignalReloadConfig:
+		return "reload-config"
+	default:
+		return fmt.Sprintf("SignalAction(%d)", int(a))
+	}
+}
+
+// effective signal->action map: HandleSignals ( or DefaultHandledSignals ) all
+// cause a shutdown, with SignalActions entries overriding/extending that
+func (uf *UFcli) signalActions() map[os.Signal]SignalAction {
+	handle := uf.HandleSignals
+	if len(handle) == 0 {
+		handle = DefaultHandledSignals
+	}
+
+	actions := make(map[os.Signal]SignalAction, len(handle)+len(uf.SignalActions))
+	for _, s := range handle {
+		actions[s] = SignalShutdown
+	}
+	for s, a := range uf.SignalActions {
+		switch a {
+		case SignalShutdown, SignalIgnore, SignalLogLevelUp, SignalLogLevelDown, SignalReloadConfig:
+			actions[s] = a
+		default:
+			uf.GetLogger().Warnf("ignoring invalid %s for signal '%s'", a, s)
+		}
+	}
+	return actions
+}
+
+// most to least verbose
+var logLevels = []string{"DEBUG", "INFO", "WARN", "ERROR"}
+
+var (
+	logLevelMu  sync.Mutex
+	logLevelIdx = 1 // INFO, as set by GetLogger()
+)
+
+func (uf *UFcli) shiftLogLevel(moreVerbose bool) {
+	logLevelMu.Lock()
+	defer logLevelMu.Unlock()
+
+	if moreVerbose && logLevelIdx > 0 {
+		logLevelIdx--
+	} else if !moreVerbose && logLevelIdx < len(logLevels)-1 {
+		logLevelIdx++
+	}
+	logging.SetLogLevel("*", logLevels[logLevelIdx]) //nolint:errcheck
+	uf.GetLogger().Warnf("log level is now %s", logLevels[logLevelIdx])
+}
+
+// absolute counterpart of shiftLogLevel, lvl must be one of logLevels
+func setLogLevel(lvl string) {
+	logLevelMu.Lock()
+	defer logLevelMu.Unlock()
+
+	for i, l := range logLevels {
+		if l == lvl {
+			logLevelIdx = i
+		}
+	}
+	logging.SetLogLevel("*", lvl) //nolint:errcheck
+}
@@ -0,0 +1,63 @@
+package ufcli
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+func TestRepeatedShutdownSignal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no SIGTERM on windows")
+	}
+	path := filepath.Join(t.TempDir(), "run.prom")
+
+	err := runHelperProcess(t, "TestRepeatedShutdownSignalHelper", "UFCLI_TEST_REPEATED_SIGNAL="+path)
+	var ee *exec.ExitError
+	if !errors.As(err, &ee) || ee.ExitCode() != 1 {
+		t.Fatalf("expected an orderly exit with code 1, got: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("FINISH metrics not written: %s", err)
+	}
+}
+
+func TestRepeatedShutdownSignalHelper(t *testing.T) {
+	path := os.Getenv("UFCLI_TEST_REPEATED_SIGNAL")
+	if path == "" {
+		t.Skip("subprocess of TestRepeatedShutdownSignal")
+	}
+
+	os.Args = []string{"ufclitest"}
+	(&UFcli{
+		MetricsTextfilePath: path,
+		AllowConcurrentRuns: true,
+		GracefulActionStop:  true,
+		BeforeShutdown: func() error {
+			// during cleanup
+			sigterm()
+			time.Sleep(100 * time.Millisecond)
+			return nil
+		},
+		AppConfig: cli.App{
+			Name: "ufclitest",
+			Action: func(cctx *cli.Context) error {
+				sigterm()
+				for !StopRequested(cctx.Context) {
+					time.Sleep(time.Millisecond)
+				}
+				// within the grace window
+				sigterm()
+				time.Sleep(100 * time.Millisecond)
+				return errors.New("stopped")
+			},
+		},
+	}).RunAndExit(context.Background())
+}
@@ -0,0 +1,59 @@
+package ufcli
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/ribasushi/go-toolbox/ufcli"
+
+// how long ending a span may wait for a provider flush, the process exits right after
+const spanFlushTimeout = 5 * time.Second
+
+// a new root span for a run starting at BEGIN, nil without a TracerProvider
+func (uf *UFcli) startRunSpan(ctx context.Context, cmd, runID string, dryRun bool) trace.Span {
+	if uf.TracerProvider == nil {
+		return nil
+	}
+	_, span := uf.TracerProvider.Tracer(tracerName).Start(
+		ctx,
+		cmd,
+		trace.WithNewRoot(),
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(
+			attribute.String("ufcli.command", cmd),
+			attribute.String("ufcli.run_id", runID),
+			attribute.Bool("ufcli.dry_run", dryRun),
+		),
+	)
+	return span
+}
+
+// the provider is flushed right away when it supports it ( as the SDK one
+// does ): once FINISH is reached the process may exit at any moment
+func (uf *UFcli) endRunSpan(span trace.Span, outcome string, runErr error) {
+	if span == nil {
+		return
+	}
+	span.SetAttributes(attribute.String("ufcli.outcome", outcome))
+	switch {
+	case runErr != nil:
+		span.RecordError(runErr)
+		span.SetStatus(codes.Error, runErr.Error())
+	case outcome == "success":
+		span.SetStatus(codes.Ok, "")
+	}
+	span.End()
+
+	if f, canFlush := uf.TracerProvider.(interface{ ForceFlush(context.Context) error }); canFlush {
+		ctx, cancel := context.WithTimeout(context.Background(), spanFlushTimeout)
+		defer cancel()
+		if err := f.ForceFlush(ctx); err != nil {
+			uf.GetLogger().Warnf("flushing trace spans failed: %s", err)
+		}
+	}
+}
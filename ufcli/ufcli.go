@@ -1,26 +1,35 @@
 package ufcli //nolint:revive
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"regexp"
 	"runtime/debug"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/BurntSushi/toml"
 	fslock "github.com/ipfs/go-fs-lock"
 	logging "github.com/ipfs/go-log/v2"
-	"github.com/mattn/go-isatty"
 	"github.com/prometheus/client_golang/prometheus"
-	prometheuspush "github.com/prometheus/client_golang/prometheus/push"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
 	"github.com/ribasushi/go-toolbox/cmn"
 	"github.com/urfave/cli/v2"
 	"github.com/urfave/cli/v2/altsrc"
-	"golang.org/x/sys/unix"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/xerrors"
 )
 
 // nolint:revive
@@ -32,46 +41,105 @@ type Logger = *logging.ZapEventLogger // FIXME make it an actual interface
 type UFcli struct {
 	AppConfig           cli.App                                                                     // stock urfavecli App configuration
 	TOMLPath            string                                                                      // optional path of TOML config file read via https://pkg.go.dev/github.com/urfave/cli/v2/altsrc
+	EnvFile             string                                                                      // optional path of a .env file loaded into the environment before any flag resolution ( overridable via --env-file )
+	EnvFileOverride     bool                                                                        // if set, values from the env file replace already-present environment variables
+	MetricsName         string                                                                      // optional operational name used as the base of metric names and lock keys, defaults to AppConfig.Name
+	MetricNameSanitizer func(string) string                                                         // optional override of how app/command names are turned into metric names and push labels, defaults to SanitizeMetricName
 	AllowConcurrentRuns bool                                                                        // if set allows multiple concurrent runs of the same command
+	LockKeyFunc         func(cctx *cli.Context) string                                              // optional lock-key refinement, appended to the app+command key when non-empty ( receives the command's cctx, see commandFlagsContext for what it can see )
+	LockPerConfig       bool                                                                        // if set, a hash of the TOMLPath content becomes part of the lock key, so that runs with distinct config files do not exclude each other
+	Locker              Locker                                                                      // optional implementation of the run lock, defaults to an FSLocker in os.TempDir()
+	IsLockConflict      func(err error) bool                                                        // optional classifier of Locker errors signifying "held by someone else" ( quietly exiting when non-interactive ), defaults to matching an fslock.LockedError
+	LockFilePerm        os.FileMode                                                                 // if set, the exact mode of the default lock file and the state files next to it ( applied via chmod, thus not subject to the process umask )
+	StartupJitter       time.Duration                                                               // if set, sleep a random amount between 0 and this value before acquiring the lock ( spreads fleet-wide scheduled starts )
+	ClockSkewCheck      *ClockSkewCheck                                                             // optional comparison of the local clock against an NTP server once, before the first run, for jobs computing time windows
+	MinInterval         time.Duration                                                               // if set, skip ( exit 0 ) a run when the previous successful one finished less than this long ago
+	MinProcessed        int64                                                                       // if set, a run reporting ( via SetProcessed/AddProcessed ) fewer processed items than this fails, not reporting at all counts as 0
+	FailOnZeroWork      bool                                                                        // shorthand for a MinProcessed of 1
+	RunTimeout          time.Duration                                                               // if set, cancel the context of the action after this long, failing the run with outcome "timeout" and TimeoutExitCode ( overridable via --timeout )
+	MaxProcs            int                                                                         // if set, the GOMAXPROCS of the run ( overridable via --maxprocs )
+	CPUAffinity         []int                                                                       // optional CPUs to pin the process to, Linux only ( overridable via --cpus ), implies a GOMAXPROCS of their count unless MaxProcs is set
+	RepeatEvery         time.Duration                                                               // if set, re-run the command on this schedule until a signal arrives, holding the lock throughout ( BEGIN/FINISH/metrics are per-iteration )
+	ProgressLogInterval time.Duration                                                               // if set, log a "still running" line this often between BEGIN and FINISH
+	RepeatStopOnErr     bool                                                                        // in RepeatEvery mode stop at the first failed iteration ( default is to log it and carry on )
+	HTTPAddr            string                                                                      // optional listen address of a /healthz + /metrics HTTP server, running for the lifetime of a RepeatEvery process
+	PromRegistry        *prometheus.Registry                                                        // optional registry of application metrics, served on HTTPAddr/metrics alongside those of the latest finished iteration
+	BatchContinueOnErr  bool                                                                        // in --batch mode keep processing subsequent lines after a failed one ( default is to abort )
 	GlobalInit          func(cctx *cli.Context, uf *UFcli) (resourceCloser func() error, err error) // optional initialization routines (setup RDBMS pool, etc)
+	Middlewares         []func(next cli.ActionFunc) cli.ActionFunc                                  // optional wrappers of every command's ( and the default ) action, Middlewares[0] being the outermost, applied per RepeatEvery iteration / --batch line
+	Warmup              func(ctx context.Context) error                                             // optional one-time preparation ( cache priming, etc ) invoked after GlobalInit and before the first run or RepeatEvery iteration, a failure aborts
+	ShouldRun           func(cctx *cli.Context) (run bool, reason string, err error)                // optional predicate evaluated after GlobalInit ( per line in --batch mode ), a false skips the run ( exit 0, no metrics )
+	CloserTimeout       time.Duration                                                               // if set, stop waiting for Background tasks, and separately for the GlobalInit resourceCloser, after this long and proceed to exit
+	CommandNotFound     func(cctx *cli.Context, attempted string)                                   // optional handler of an unknown command ( exiting with code 3 afterwards ), defaults to SuggestCommand
+	ExclusiveFlagGroups map[string][][]string                                                       // optional per-command ( "Action" for an app without subcommands ) groups of flags of which exactly one must be set, see RequireExactlyOne
+	CommandAliases      map[string]string                                                           // optional map of retired command names to their current ones, resolved before dispatch with a deprecation warning ( logs, metrics and locks use the current name )
+	CommandProvider     func(cctx *cli.Context) ([]*cli.Command, error)                             // optional source of additional commands, invoked after the config file is read and before dispatch
+	OnSuccess           func(cctx *cli.Context, sum RunSummary) error                               // optional hook invoked after the FINISH of every successful run ( e.g. to fire a webhook ), an error is logged, see FailOnCleanupError
+	OnFailure           func(cctx *cli.Context, sum RunSummary, err error) error                    // optional hook invoked after the FINISH of every failed run, an error is logged, see FailOnCleanupError
+	FailOnCleanupError  bool                                                                        // if set, an error of OnSuccess/OnFailure makes an otherwise successful process exit with 1
 	BeforeShutdown      func() error                                                                // optional function to execute before the top context is cancelled ( unlike resourceCloser above )
+	GracefulActionStop  bool                                                                        // if set, a shutdown signal does not cancel the context of an in-flight action right away: it is expected to poll StopRequested and return at a safe point
+	GracefulStopTimeout time.Duration                                                               // under GracefulActionStop, how long to wait for the action to return before cancelling its context after all, defaults to 1 minute
 	HandleSignals       []os.Signal                                                                 // if empty defaults to DefaultHandledSignals
+	SignalActions       map[os.Signal]SignalAction                                                  // optional per-signal behavior, overriding/extending the SignalShutdown implied by HandleSignals
+	OnConfigReload      func(cctx *cli.Context) error                                               // optional hook invoked after a SignalReloadConfig-triggered re-read of TOMLPath applied the new values, an error reverts them
+	OnSignal            func(sig os.Signal)                                                         // optional hook invoked upon receipt of a handled signal, before shutdown begins ( a panic within is logged and ignored )
+	TracerProvider      trace.TracerProvider                                                        // optional OpenTelemetry provider: every run becomes a root span from BEGIN to FINISH, available to the action via its ctx
 	Logger              Logger                                                                      // optional ZapEventLogger-compatible object
+	MetricsTextfilePath string                                                                      // optional `.prom` file the FINISH metrics are atomically written to ( for node_exporter's textfile collector ), for failed runs too
+	OnMetricsPush       func(url string, err error)                                                 // optional hook invoked with the outcome of every metrics push attempt
+	IncludeHostGrouping bool                                                                        // if set, metrics are pushed with an additional host=<os.Hostname()> grouping
+	PushSuccessGrouping bool                                                                        // if set, metrics are pushed with an additional success="true|false" grouping ( see below )
+	MetricLabelFlags    map[string][]string                                                         // optional per-command ( "Action" for an app without subcommands ) flags whose values become pushgateway groupings, e.g. shard="3" ( unset, secret-looking and overly long values are skipped )
+	PushOnFailureOnly   bool                                                                        // if set, metrics are pushed only for failed runs ( see below ), the FINISH log, textfile and HTTPAddr are unaffected
+	PushOnFailureOnlyBy map[string]bool                                                             // optional per-command override of PushOnFailureOnly, keyed by command name ( "Action" for an app without subcommands )
+	ErrorReportPath     string                                                                      // optional file a JSON report of a failed run is atomically written to, in --batch mode that of the last failed line ( or of the batch as a whole, with a command of `--batch` )
+	EventSocketPath     string                                                                      // optional Unix socket receiving newline-delimited JSON begin/finish/skip events ( best-effort, a missing listener does not affect the run )
 
-	currentCmdLock io.Closer // to hang on to until object destruction
-
+	currentCmdLock    io.Closer // to hang on to until object destruction
+	eventSocketWarned bool
 }
 
 // nolint:revive
-var DefaultHandledSignals = []os.Signal{
-	unix.SIGTERM,
-	unix.SIGINT,
-	unix.SIGHUP,
-	unix.SIGPIPE,
-}
+var DefaultHandledSignals = cmn.DefaultSignals
+
+// TimeoutExitCode is the exit code of a run exceeding its RunTimeout, the same as of coreutils' timeout(1)
+const TimeoutExitCode = 124
+
+const defaultGracefulStopTimeout = time.Minute
+
+var errRunTimeout = errors.New("run timeout exceeded")
 
 // RunAndExit will excute any init routines, run the app, and os.Exit() after shutdown
 func (uf *UFcli) RunAndExit(parentCtx context.Context) {
 	ctx, topCtxShutdown := context.WithCancel(parentCtx)
+	rs := newRunState(uf)
+	ctx = withRunState(ctx, rs)
 
 	var resourcesCloser func() error
+	var interrupted atomic.Bool   // set when shutdown is signal-initiated while the action is running
+	var actionRunning atomic.Bool // for the duration of an action ( a RepeatEvery iteration / --batch line )
+	var reloadPending atomic.Bool // set by SignalReloadConfig, acted upon between RepeatEvery iterations
 	var o sync.Once
 	// called from the defer below
 	shutdown := func(isNormal bool) {
 		o.Do(func() {
 
-			if uf.BeforeShutdown != nil {
-				if err := uf.BeforeShutdown(); err != nil {
+			if !cmn.IsNil(uf.BeforeShutdown) {
+				if err := cmn.WithRecover(uf.BeforeShutdown)(); err != nil {
 					uf.GetLogger().Warnf("error encountered during before-shutdown cleanup: %+v", err)
 				}
 			}
 
 			topCtxShutdown()
 
-			if resourcesCloser != nil {
-				if err := resourcesCloser(); err != nil {
-					uf.GetLogger().Warnf("error encountered during after-shutdown cleanup: %+v", err)
-				}
+			uf.awaitBackground(rs)
+
+			// before the resources, which they may well be writing to
+			uf.closeResources(rs.closeRegistered)
+
+			if !cmn.IsNil(resourcesCloser) {
+				uf.closeResources(resourcesCloser)
 			}
 
 			if !isNormal {
@@ -80,75 +148,735 @@ func (uf *UFcli) RunAndExit(parentCtx context.Context) {
 		})
 	}
 
-	go func() {
-		handle := uf.HandleSignals
-		if len(handle) == 0 {
-			handle = DefaultHandledSignals
+	var shutdownSigs, otherSigs []os.Signal
+	sigActions := uf.signalActions()
+	for s, a := range sigActions {
+		switch a {
+		case SignalShutdown:
+			shutdownSigs = append(shutdownSigs, s)
+		case SignalIgnore:
+			signal.Ignore(s)
+		default:
+			otherSigs = append(otherSigs, s)
 		}
+	}
+
+	if len(shutdownSigs) > 0 {
+		// SignalContext stops the notification upon the first signal: keep catching them until
+		// os.Exit(), lest a repeated one kill the process mid-cleanup via its default action
+		repeatSigs := make(chan os.Signal, 1)
+		signal.Notify(repeatSigs, shutdownSigs...)
+		go func() {
+			var seen int
+			for sig := range repeatSigs {
+				if seen++; seen > 1 {
+					uf.GetLogger().Warnf("termination signal '%s' received again, shutdown already in progress", sig)
+				}
+			}
+		}()
+
+		sigCtx, _ := cmn.SignalContext(parentCtx, shutdownSigs...) // never cancelled: we os.Exit() at the end
+		go func() {
+			<-sigCtx.Done()
+			sig, isSig := cmn.ContextSignal(sigCtx)
+			if !isSig {
+				return
+			}
+			// a completed run is not cut short by a signal arriving during its cleanup
+			if actionRunning.Load() {
+				interrupted.Store(true)
+			}
+			rs.requestStop()
+			if uf.GracefulActionStop {
+				uf.GetLogger().Warnf("termination signal '%s' received, requesting the action to stop...", sig)
+			} else {
+				uf.GetLogger().Warnf("termination signal '%s' received, cleaning up...", sig)
+			}
+			if !cmn.IsNil(uf.OnSignal) {
+				if err := cmn.WithRecover(func() error { uf.OnSignal(sig); return nil })(); err != nil {
+					uf.GetLogger().Errorf("OnSignal hook failed: %+v", err)
+				}
+			}
+
+			// the process exits as soon as the action returns: only the deadline is of interest
+			if uf.GracefulActionStop {
+				timeout := uf.GracefulStopTimeout
+				if timeout <= 0 {
+					timeout = defaultGracefulStopTimeout
+				}
+				time.Sleep(timeout)
+				uf.GetLogger().Warnf("action did not stop within %s, cleaning up...", cmn.HumanDuration(timeout))
+			}
+			shutdown(false)
+		}()
+	}
+
+	if len(otherSigs) > 0 {
 		sigs := make(chan os.Signal, 1)
-		signal.Notify(sigs, handle...)
-		<-sigs
-		uf.GetLogger().Warn("termination signal received, cleaning up...")
-		shutdown(false)
-	}()
+		signal.Notify(sigs, otherSigs...)
+		go func() {
+			for sig := range sigs {
+				switch sigActions[sig] {
+				case SignalLogLevelUp:
+					uf.shiftLogLevel(true)
+				case SignalLogLevelDown:
+					uf.shiftLogLevel(false)
+				case SignalReloadConfig:
+					if uf.TOMLPath == "" || uf.RepeatEvery <= 0 {
+						uf.GetLogger().Warnf("ignoring signal '%s': config reload is only available in RepeatEvery mode with a TOMLPath", sig)
+						continue
+					}
+					reloadPending.Store(true)
+					uf.GetLogger().Infof("signal '%s' received, config will be reloaded before the next iteration", sig)
+				}
+			}
+		}()
+	}
 
 	// BIZARRE inverted flow because... scoping
 	var (
 		startTime    time.Time
+		notFoundCmd  string
+		topCctx      *cli.Context
+		explicit     map[string]struct{} // flags set on the command line or environment, not subject to reload
+		runID        string
 		scopeErr     error
 		didBegin     bool
 		currentCmd   string
-		promPushConf struct {
-			url      string
-			user     string
-			pass     string
-			instance string
-		}
+		runKey       string
+		configHash   string // see LockPerConfig
+		runTimeout   time.Duration
+		maxProcs     int // the GOMAXPROCS set via MaxProcs/CPUAffinity, 0 if neither
+		timedOut     bool
+		noMetrics    bool
+		reportJSON   bool
+		pushFailures int          // for the lifetime of the process
+		hookFailed   bool         // see FailOnCleanupError
+		lastRunStats atomic.Value // []prometheus.Collector of the latest FINISH, served over HTTPAddr
+		prof         *profiler
+		heartbeat    chan struct{} // closed to stop the ProgressLogInterval logger
+		runSpan      trace.Span    // nil without a TracerProvider
+		cmdNames     map[string]string
+		promPushConf pushConf
+		runArgs      = os.Args
+		usedAlias    string         // see CommandAliases
+		batchRun     *batchIdentity // set in --batch mode
 	)
-	emitEndLogs := func(wasSuccess bool) {
+	runOutcome := func(wasSuccess bool) string {
+		if interrupted.Load() {
+			return "interrupted"
+		} else if timedOut {
+			return "timeout"
+		} else if !wasSuccess {
+			return "failure"
+		}
+		return "success"
+	}
+	stopHeartbeat := func() {
+		if heartbeat != nil {
+			close(heartbeat)
+			heartbeat = nil
+		}
+	}
+	startHeartbeat := func() {
+		stopHeartbeat()
+		if uf.ProgressLogInterval <= 0 {
+			return
+		}
+		stop, cmd, since := make(chan struct{}), currentCmd, startTime
+		heartbeat = stop
+		go func() {
+			t := time.NewTicker(uf.ProgressLogInterval)
+			defer t.Stop()
+			for {
+				select {
+				case <-stop:
+					return
+				case <-ctx.Done():
+					return
+				case <-t.C:
+					uf.GetLogger().Infow(fmt.Sprintf("=== '%s' still running", cmd), "elapsed", cmn.HumanDuration(time.Since(since)))
+				}
+			}
+		}()
+	}
+	emitEndLogs := func(runErr error) {
+		stopHeartbeat()
+
 		// no FINISH without BEGIN
 		if !didBegin {
 			return
 		}
 
-		took := time.Since(startTime).Truncate(time.Millisecond)
+		wasSuccess := runErr == nil
+		outcome := runOutcome(wasSuccess)
+
+		took := time.Since(startTime)
 		logHdr := fmt.Sprintf("=== FINISH '%s' run", currentCmd)
 		logArgs := []interface{}{
 			"success", wasSuccess,
-			"took", took.String(),
+			"outcome", outcome,
+			"took", cmn.HumanDuration(took),
 		}
 
-		cmdFqName := promStr(uf.AppConfig.Name + "_" + currentCmd)
-		tookGauge := prometheus.NewGauge(prometheus.GaugeOpts{
-			Name: fmt.Sprintf("%s_run_time", cmdFqName),
-			Help: "How long did the job take (in milliseconds)",
-		})
-		tookGauge.Set(float64(took.Milliseconds()))
-		successGauge := prometheus.NewGauge(prometheus.GaugeOpts{
-			Name: fmt.Sprintf("%s_success", cmdFqName),
-			Help: "Whether the job completed with success(1) or failure(0)",
-		})
+		cmdFqName := uf.cmdFqName(currentCmd)
+		runCollectors := func(constLabels prometheus.Labels) []prometheus.Collector {
+			var isSuccess, isInterrupted float64
+			if wasSuccess {
+				isSuccess = 1
+			}
+			if interrupted.Load() {
+				isInterrupted = 1
+			}
+			collectors := []prometheus.Collector{
+				metricRunTime.gauge(cmdFqName, constLabels, float64(took.Milliseconds())),
+				metricSuccess.gauge(cmdFqName, constLabels, isSuccess),
+				metricInterrupted.gauge(cmdFqName, constLabels, isInterrupted),
+			}
+			if rs.processedSet.Load() {
+				collectors = append(collectors, metricProcessed.gauge(cmdFqName, constLabels, float64(rs.processed.Load())))
+			}
+			for _, st := range rs.stageTimes() {
+				collectors = append(collectors, stageMetric(st.name).gauge(cmdFqName, constLabels, st.took.Seconds()))
+			}
+			return collectors
+		}
+
+		// a pushgateway refuses labels clashing with a grouping: pushes get a dry_run
+		// grouping instead, keeping dry runs from overwriting the real ones
+		var dryRunLabels prometheus.Labels
+		if rs.dryRun {
+			dryRunLabels = prometheus.Labels{"dry_run": "true"}
+		}
+		collectors := runCollectors(dryRunLabels)
+		lastRunStats.Store(collectors)
+
+		// regardless of outcome ( and of --no-metrics ): a failure is written with a success of 0, while
+		// the node_textfile_mtime_seconds of the file tells when that happened
+		if uf.MetricsTextfilePath != "" {
+			if err := writePromTextfile(uf.MetricsTextfilePath, collectors...); err != nil {
+				uf.GetLogger().Warnf("writing prometheus metrics to '%s' failed: %+v", uf.MetricsTextfilePath, err)
+			}
+		}
 
+		// NOTE: with PushOnFailureOnly the pushgateway goes on serving the latest failure ( success=0 )
+		// after any number of subsequent successes: alert on the push_time_seconds of the group ( the
+		// time of the latest failure ) being recent, rather than on the value of the success gauge
+		if promPushConf.url != "" && !noMetrics && !(wasSuccess && uf.pushOnFailureOnly(currentCmd)) {
+			var labels map[string]string
+			if l := rs.flagLabels.Load(); l != nil {
+				labels = *l
+			}
+			p := uf.newPusher(promPushConf, currentCmd, labels)
+			// NOTE: a grouping is part of the pushgateway key: successes and failures become two
+			// separate groups, each retaining its latest values indefinitely ( doubling the series
+			// per command ), and a stale failure group stays visible until deleted
+			if uf.PushSuccessGrouping {
+				p = p.Grouping("success", strconv.FormatBool(wasSuccess))
+			}
+			pushCollectors := collectors
+			if rs.dryRun {
+				p = p.Grouping("dry_run", "true")
+				pushCollectors = runCollectors(nil)
+			}
+			for _, c := range pushCollectors {
+				p = p.Collector(c)
+			}
+			promErr := p.Push()
+			if promErr != nil {
+				pushFailures++
+				uf.GetLogger().Warnf("push of prometheus metrics to '%s' failed: %s", promPushConf.url, promErr)
+			}
+			if !cmn.IsNil(uf.OnMetricsPush) {
+				if err := cmn.WithRecover(func() error { uf.OnMetricsPush(promPushConf.url, promErr); return nil })(); err != nil {
+					uf.GetLogger().Errorf("OnMetricsPush hook failed: %+v", err)
+				}
+			}
+		}
+
+		// log after the push, so that its outcome can be reported
+		if pushFailures > 0 {
+			logArgs = append(logArgs, "metrics_push_failures", pushFailures)
+		}
+		if rs.dryRun {
+			logArgs = append(logArgs, "dry_run", true)
+		}
+		if rs.processedSet.Load() {
+			logArgs = append(logArgs, "processed", rs.processed.Load())
+		}
 		if wasSuccess {
 			uf.GetLogger().Infow(logHdr, logArgs...)
-			successGauge.Set(1)
 		} else {
 			uf.GetLogger().Warnw(logHdr, logArgs...)
-			successGauge.Set(0)
+		}
+		uf.sendEvent(lifecycleEvent{Event: "finish", Command: currentCmd, RunID: runID, Success: &wasSuccess, Outcome: outcome})
+		uf.endRunSpan(runSpan, outcome, runErr)
+		runSpan = nil
+
+		if err := uf.runOutcomeHook(topCctx, RunSummary{
+			Command:      currentCmd,
+			RunID:        runID,
+			Outcome:      outcome,
+			Started:      startTime,
+			Took:         took,
+			DryRun:       rs.dryRun,
+			Processed:    rs.processed.Load(),
+			ProcessedSet: rs.processedSet.Load(),
+			Fields:       logArgs,
+		}, runErr); err != nil {
+			hookFailed = true
 		}
 
-		if promPushConf.url != "" {
-			p := prometheuspush.New(promPushConf.url, promStr(currentCmd))
-			if promPushConf.instance != "" {
-				p = p.Grouping("instance", promStr(promPushConf.instance))
+		if reportJSON {
+			rep := struct {
+				Command string `json:"command"`
+				RunID   string `json:"run_id"`
+				Success bool   `json:"success"`
+				Outcome string `json:"outcome"`
+				TookMs  int64  `json:"took_ms"`
+				Error   string `json:"error,omitempty"`
+			}{
+				Command: currentCmd,
+				RunID:   runID,
+				Success: wasSuccess,
+				Outcome: outcome,
+				TookMs:  took.Milliseconds(),
 			}
-			if promPushConf.user != "" {
-				p = p.BasicAuth(promPushConf.user, promPushConf.pass)
+			if runErr != nil {
+				rep.Error = runErr.Error()
 			}
-			if promErr := p.Collector(tookGauge).Collector(successGauge).Push(); promErr != nil {
-				uf.GetLogger().Warnf("push of prometheus metrics to '%s' failed: %s", promPushConf.url, promErr)
+			if j, err := json.Marshal(rep); err != nil {
+				uf.GetLogger().Warnf("encoding of run report failed: %s", err)
+			} else {
+				fmt.Fprintf(os.Stdout, "%s\n", j)
+			}
+		}
+	}
+	recordSuccess := func() {
+		if uf.MinInterval > 0 && didBegin {
+			if err := cmn.WriteJSONFilePerm(
+				lastSuccessPath(runKey),
+				lastSuccessState{Finished: time.Now()},
+				uf.stateFilePerm(),
+			); err != nil {
+				uf.GetLogger().Warnf("failed to record last successful run: %+v", err)
+			}
+		}
+	}
+
+	writeErrorReportAs := func(cmd, id string, started time.Time, err error) {
+		if uf.ErrorReportPath == "" {
+			return
+		}
+		rep := struct {
+			RunID   string `json:"run_id"`
+			Command string `json:"command"`
+			cmn.ErrJSON
+			Outcome string    `json:"outcome"`
+			Started time.Time `json:"started"`
+			Ended   time.Time `json:"ended"`
+		}{
+			RunID:   id,
+			Command: cmd,
+			ErrJSON: cmn.ErrToJSON(err),
+			Outcome: runOutcome(false),
+			Started: started,
+			Ended:   time.Now(),
+		}
+		j, jErr := json.MarshalIndent(rep, "", "  ")
+		if jErr == nil {
+			jErr = cmn.AtomicWriteFile(uf.ErrorReportPath, append(j, '\n'), 0644)
+		}
+		if jErr != nil {
+			uf.GetLogger().Warnf("writing error report to '%s' failed: %+v", uf.ErrorReportPath, jErr)
+		}
+	}
+	writeErrorReport := func(err error) {
+		writeErrorReportAs(currentCmd, runID, startTime, err)
+		if batchRun != nil {
+			batchRun.lineReported = true
+		}
+	}
+	// in --batch mode the report of the failed line is the more useful one: the
+	// batch-level error is reported only when no line got that far
+	writeScopeErrorReport := func(err error) {
+		if batchRun == nil {
+			writeErrorReport(err)
+		} else if !batchRun.lineReported {
+			writeErrorReportAs(batchReportCommand, batchRun.id, batchRun.started, err)
+		}
+	}
+
+	readPushConf := func(cctx *cli.Context) {
+		promPushConf.url = cctx.String("prometheus_push_url")
+		promPushConf.user = cctx.String("prometheus_push_user")
+		promPushConf.pass = cctx.String("prometheus_push_pass")
+		promPushConf.instance = cctx.String("prometheus_instance")
+		if promPushConf.url != "" && !noMetrics {
+			pc := promPushConf
+			rs.push.Store(&pc)
+		} else {
+			rs.push.Store(nil)
+		}
+	}
+
+	logBegin := func() {
+		var logArgs []interface{}
+		if rs.dryRun {
+			logArgs = append(logArgs, "dry_run", true)
+		}
+		if maxProcs > 0 {
+			logArgs = append(logArgs, "gomaxprocs", maxProcs)
+		}
+		uf.GetLogger().Infow(fmt.Sprintf("=== BEGIN '%s' run", currentCmd), logArgs...)
+		uf.sendEvent(lifecycleEvent{Event: "begin", Command: currentCmd, RunID: runID})
+		runSpan = uf.startRunSpan(ctx, currentCmd, runID, rs.dryRun)
+		didBegin = true
+		rs.reset(currentCmd, runID)
+		startHeartbeat()
+	}
+
+	// lock/BEGIN for the already-determined currentCmd
+	beginRun := func(cctx *cli.Context) error {
+		runKey = promStr(uf.metricsName()) + "-" + promStr(currentCmd) // reuse promstr as path-safe stuff
+		if !cmn.IsNil(uf.LockKeyFunc) {
+			if refinement := uf.LockKeyFunc(cctx); refinement != "" {
+				runKey += "-" + promStr(refinement)
+			}
+		}
+		if configHash != "" {
+			runKey += "-" + configHash
+		}
+
+		if !uf.AllowConcurrentRuns {
+			var l Locker = &FSLocker{Perm: uf.LockFilePerm}
+			if !cmn.IsNil(uf.Locker) {
+				l = uf.Locker
+			}
+			var err error
+			if uf.currentCmdLock, err = l.Lock(runKey); err != nil {
+				return err // no xerrors wrap on purpose
+			}
+		}
+
+		// check under the lock, so that a concurrent run could not have just finished
+		if uf.MinInterval > 0 {
+			if last, found := uf.readLastSuccess(runKey); found {
+				if since := time.Since(last); since < uf.MinInterval {
+					return &skipRun{reason: fmt.Sprintf(
+						"last successful run finished %s ago, less than the minimum interval of %s",
+						cmn.HumanDuration(since),
+						cmn.HumanDuration(uf.MinInterval),
+					)}
+				}
+			}
+		}
+
+		logBegin()
+		return nil
+	}
+
+	checkShouldRun := func(cctx *cli.Context) error {
+		if cmn.IsNil(uf.ShouldRun) {
+			return nil
+		}
+		run, reason, err := uf.ShouldRun(cctx)
+		if err != nil {
+			return cmn.WrErr(err)
+		}
+		if !run {
+			if reason == "" {
+				reason = "ShouldRun predicate declined"
+			}
+			return &skipRun{reason: reason}
+		}
+		return nil
+	}
+
+	// RepeatEvery: the lock/BEGIN of the first iteration is handled by Before()
+	repeatAction := func(next cli.ActionFunc) cli.ActionFunc {
+		return func(cctx *cli.Context) error {
+			if uf.HTTPAddr != "" {
+				gs := prometheus.Gatherers{prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+					reg := prometheus.NewRegistry()
+					if cs, ok := lastRunStats.Load().([]prometheus.Collector); ok {
+						for _, c := range cs {
+							if err := reg.Register(c); err != nil {
+								return nil, err
+							}
+						}
+					}
+					return reg.Gather()
+				})}
+				if uf.PromRegistry != nil {
+					gs = append(gs, uf.PromRegistry)
+				}
+				if err := uf.serveHTTP(ctx, gs); err != nil {
+					return err
+				}
+			}
+
+			for {
+				iterStart := startTime
+				err := next(cctx)
+
+				// interrupted mid-iteration: let the defer handle the FINISH
+				if ctx.Err() != nil || rs.stopRequested() {
+					return err
+				}
+
+				if err != nil {
+					if uf.RepeatStopOnErr {
+						return err
+					}
+					uf.GetLogger().Errorf("%+v", err)
+					emitEndLogs(err)
+					writeErrorReport(err)
+				} else {
+					emitEndLogs(nil)
+					recordSuccess()
+				}
+				didBegin = false
+
+				t := time.NewTimer(time.Until(iterStart.Add(uf.RepeatEvery)))
+				select {
+				case <-ctx.Done():
+					t.Stop()
+					return nil
+				case <-rs.stopped:
+					t.Stop()
+					return nil
+				case <-t.C:
+				}
+
+				if reloadPending.Swap(false) {
+					if changed, err := uf.reloadConfig(topCctx, explicit); err != nil {
+						uf.GetLogger().Errorf("config reload failed, retaining previous values: %+v", err)
+					} else {
+						readPushConf(topCctx)
+						uf.GetLogger().Infow("config reloaded", "changed", changed)
+					}
+				}
+
+				startTime = time.Now()
+				runID = cmn.NewIDAt(startTime)
+				logBegin()
+			}
+		}
+	}
+
+	// the deadline applies to a single RepeatEvery iteration / --batch line, middlewares included
+	timeoutAction := func(next cli.ActionFunc) cli.ActionFunc {
+		return func(cctx *cli.Context) error {
+			timedOut = false
+			if runTimeout <= 0 {
+				return next(cctx)
+			}
+
+			parentCtx := cctx.Context
+			tCtx, tCancel := context.WithTimeoutCause(parentCtx, runTimeout, errRunTimeout)
+			defer tCancel()
+			cctx.Context = tCtx
+			err := next(cctx)
+			cctx.Context = parentCtx
+
+			// an overrun is a timeout, regardless of whether the action noticed
+			if context.Cause(tCtx) == errRunTimeout { //nolint:errorlint
+				timedOut = true
+				if err == nil {
+					err = errRunTimeout
+				}
+				return ExitError(TimeoutExitCode, cmn.WrErr(fmt.Errorf(
+					"run did not complete within %s: %w",
+					cmn.HumanDuration(runTimeout),
+					err,
+				)))
+			}
+			return err
+		}
+	}
+
+	minProcessed := uf.MinProcessed
+	if minProcessed < 1 && uf.FailOnZeroWork {
+		minProcessed = 1
+	}
+	workAction := func(next cli.ActionFunc) cli.ActionFunc {
+		return func(cctx *cli.Context) error {
+			if err := next(cctx); err != nil || minProcessed <= 0 {
+				return err
+			}
+			if n := rs.processed.Load(); n < minProcessed {
+				if !rs.processedSet.Load() {
+					return cmn.WrErr(fmt.Errorf("action did not report its processed item count, while at least %d are required", minProcessed))
+				}
+				return cmn.WrErr(fmt.Errorf("action processed %d items, fewer than the required minimum of %d", n, minProcessed))
+			}
+			return nil
+		}
+	}
+
+	// the config file was applied by now, and its values count as set
+	flagsAction := func(next cli.ActionFunc) cli.ActionFunc {
+		return func(cctx *cli.Context) error {
+			if labels := uf.flagLabels(cctx, currentCmd); len(labels) > 0 {
+				rs.flagLabels.Store(&labels)
+			}
+			for _, g := range uf.ExclusiveFlagGroups[currentCmd] {
+				if err := RequireExactlyOne(cctx, g...); err != nil {
+					return err
+				}
+			}
+			return next(cctx)
+		}
+	}
+
+	// a span of its own per RepeatEvery iteration / --batch line
+	traceAction := func(next cli.ActionFunc) cli.ActionFunc {
+		return func(cctx *cli.Context) error {
+			if runSpan == nil {
+				return next(cctx)
+			}
+			parentCtx := cctx.Context
+			cctx.Context = trace.ContextWithSpan(parentCtx, runSpan)
+			defer func() { cctx.Context = parentCtx }()
+			return next(cctx)
+		}
+	}
+
+	runningAction := func(next cli.ActionFunc) cli.ActionFunc {
+		return func(cctx *cli.Context) error {
+			actionRunning.Store(true)
+			defer actionRunning.Store(false)
+			return next(cctx)
+		}
+	}
+
+	decorateAction := func(a cli.ActionFunc) cli.ActionFunc {
+		a = workAction(a)
+		for i := len(uf.Middlewares) - 1; i >= 0; i-- {
+			a = uf.Middlewares[i](a)
+		}
+		a = flagsAction(a)
+		a = runningAction(traceAction(timeoutAction(a)))
+		if uf.RepeatEvery > 0 {
+			a = repeatAction(a)
+		}
+		return a
+	}
+
+	// shallow copies: do not modify the caller's AppConfig
+	decorateCommands := func(cmds []*cli.Command) []*cli.Command {
+		ret := make([]*cli.Command, len(cmds))
+		for i, c := range cmds {
+			cc := *c
+			if cc.Action != nil {
+				cc.Action = decorateAction(cc.Action)
+			}
+			ret[i] = &cc
+		}
+		return ret
+	}
+
+	// each line of a --batch is a complete run, sharing the GlobalInit resources
+	runBatch := func(cctx *cli.Context, batchPath string) error {
+		batchStart := time.Now()
+		batchRun = &batchIdentity{id: cmn.NewIDAt(batchStart), started: batchStart}
+
+		entries, err := readBatchEntries(batchPath)
+		if err != nil {
+			return err
+		}
+		if err := uf.checkClockSkew(ctx); err != nil {
+			return err
+		}
+
+		if !cmn.IsNil(uf.GlobalInit) {
+			if resourcesCloser, err = uf.GlobalInit(cctx, uf); err != nil {
+				return cmn.WrErr(err)
+			}
+		}
+		if !cmn.IsNil(uf.Warmup) {
+			if err := uf.Warmup(ctx); err != nil {
+				return cmn.WrErr(err)
+			}
+		}
+
+		var succeeded, failed, skipped int
+		for _, e := range entries {
+			if ctx.Err() != nil {
+				return cmn.WrErr(fmt.Errorf("batch interrupted before line %d: %w", e.lineNo, ctx.Err()))
+			} else if rs.stopRequested() {
+				return cmn.WrErr(fmt.Errorf("batch interrupted before line %d: stop requested", e.lineNo))
+			}
+
+			startTime = time.Now()
+			runID = cmn.NewIDAt(startTime)
+			if canonical, isAlias := uf.CommandAliases[e.args[0]]; isAlias {
+				uf.warnCommandAlias(e.args[0])
+				e.args[0] = canonical
+			}
+			currentCmd = cmdNames[e.args[0]]
+
+			var runErr error
+			if currentCmd == "" {
+				runErr = fmt.Errorf("unknown command '%s'", e.args[0])
+			} else {
+				// give ShouldRun a view of the line's arguments
+				fs := flag.NewFlagSet(currentCmd, flag.ContinueOnError)
+				fs.Parse(append([]string{"--"}, e.args...)) //nolint:errcheck
+				lineCtx := cli.NewContext(cctx.App, fs, cctx)
+
+				if runErr = beginRun(commandFlagsContext(cctx, currentCmd, e.args[1:])); runErr == nil {
+					runErr = checkShouldRun(lineCtx)
+				}
+				if runErr == nil {
+					lineCtx = cli.NewContext(cctx.App, nil, cctx)
+					lineCtx.Command = cctx.App.Command(currentCmd)
+					runErr = lineCtx.Command.Run(lineCtx, e.args...)
+				}
+			}
+
+			var skip *skipRun
+			switch {
+			case errors.As(runErr, &skip):
+				skipped++
+				uf.GetLogger().Infow(fmt.Sprintf("=== SKIP '%s' run", currentCmd), "skipped", true, "reason", skip.reason, "batch_line", e.lineNo)
+				uf.endRunSpan(runSpan, "skipped", nil)
+				runSpan = nil
+				uf.sendEvent(lifecycleEvent{Event: "skip", Command: currentCmd, RunID: runID, Reason: skip.reason})
+			case runErr != nil:
+				failed++
+				uf.GetLogger().Errorf("batch line %d: %+v", e.lineNo, runErr)
+				emitEndLogs(runErr)
+				writeErrorReport(runErr)
+			default:
+				succeeded++
+				emitEndLogs(nil)
+				recordSuccess()
+			}
+
+			if uf.currentCmdLock != nil {
+				uf.currentCmdLock.Close() //nolint:errcheck
+				uf.currentCmdLock = nil
+			}
+			didBegin = false
+
+			if runErr != nil && skip == nil && !uf.BatchContinueOnErr {
+				return cmn.WrErr(fmt.Errorf("batch aborted: line %d failed", e.lineNo))
 			}
 		}
+
+		uf.GetLogger().Infow(
+			"=== BATCH complete",
+			"batch_id", batchRun.id,
+			"succeeded", succeeded,
+			"failed", failed,
+			"skipped", skipped,
+		)
+		if failed > 0 {
+			return cmn.WrErr(fmt.Errorf("%d of %d batch lines failed", failed, len(entries)))
+		}
+		return nil
 	}
 	// end BIZARRE
 
@@ -164,28 +892,77 @@ func (uf *UFcli) RunAndExit(parentCtx context.Context) {
 			}
 		}
 
+		if prof != nil {
+			if err := prof.stop(); err != nil {
+				uf.GetLogger().Warnf("writing profiles failed: %+v", err)
+			}
+		}
+
 		if scopeErr != nil {
+			// a skip is not a failure, and does not emit metrics
+			var skip *skipRun
+			if errors.As(scopeErr, &skip) {
+				uf.GetLogger().Infow(fmt.Sprintf("=== SKIP '%s' run", currentCmd), "skipped", true, "reason", skip.reason)
+				uf.endRunSpan(runSpan, "skipped", nil)
+				uf.sendEvent(lifecycleEvent{Event: "skip", Command: currentCmd, RunID: runID, Reason: skip.reason})
+				shutdown(true)
+				os.Exit(0)
+			}
+
 			// if we are not interactive - be quiet on a failed lock
-			if !uf.AllowConcurrentRuns && errors.As(scopeErr, new(fslock.LockedError)) && !isatty.IsTerminal(os.Stderr.Fd()) {
+			if !uf.AllowConcurrentRuns && uf.isLockConflict(scopeErr) && !cmn.IsInteractive() {
 				shutdown(true)
+				writeScopeErrorReport(scopeErr)
 				os.Exit(1)
 			}
 
+			exitCode := 1
+			var ee *exitError
+			if errors.As(scopeErr, &ee) {
+				exitCode = ee.code
+			}
+
 			uf.GetLogger().Errorf("%+v", scopeErr)
 			shutdown(false)
-			emitEndLogs(false)
-			os.Exit(1)
+			emitEndLogs(scopeErr)
+			writeScopeErrorReport(scopeErr)
+			os.Exit(exitCode)
+		}
+
+		// nothing was run: not a success, but neither an error worth logging
+		if notFoundCmd != "" {
+			shutdown(true)
+			os.Exit(commandNotFoundExitCode)
 		}
 
 		shutdown(true)
-		emitEndLogs(true)
+		emitEndLogs(nil)
+		recordSuccess()
+		if hookFailed && uf.FailOnCleanupError {
+			os.Exit(1)
+		}
 		os.Exit(0)
 	}()
 
 	startTime = time.Now()
+	runID = cmn.NewIDAt(startTime)
 
 	app := uf.AppConfig
 	app.ExitErrHandler = func(*cli.Context, error) {}
+	notFoundHandler := SuggestCommand
+	if !cmn.IsNil(uf.CommandNotFound) {
+		notFoundHandler = uf.CommandNotFound
+	} else if !cmn.IsNil(app.CommandNotFound) {
+		notFoundHandler = app.CommandNotFound
+	}
+	app.CommandNotFound = func(cctx *cli.Context, attempted string) {
+		notFoundCmd = attempted
+		notFoundHandler(cctx, attempted)
+	}
+	app.Commands = decorateCommands(app.Commands)
+	if app.Action != nil {
+		app.Action = decorateAction(app.Action)
+	}
 
 	for _, s := range []string{
 		"prometheus_push_url",
@@ -199,6 +976,45 @@ func (uf *UFcli) RunAndExit(parentCtx context.Context) {
 			Hidden:      true,
 		}))
 	}
+	app.Flags = append(app.Flags,
+		&cli.BoolFlag{
+			Name:  "no-metrics",
+			Usage: "do not push metrics for this run ( FINISH is still logged )",
+		},
+		&cli.BoolFlag{
+			Name:  "quiet",
+			Usage: "log only errors ( metrics are unaffected )",
+		},
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "ask the command to not make any changes ( support varies by command ), marking logs and metrics accordingly",
+		},
+		&cli.BoolFlag{
+			Name:   "list-metrics",
+			Usage:  "print the names of the metrics every command emits and exit",
+			Hidden: true,
+		},
+		&cli.BoolFlag{
+			Name:  "report-json",
+			Usage: "print a JSON summary of every finished run to stdout ( logs go to stderr )",
+		},
+		&cli.DurationFlag{
+			Name:  "timeout",
+			Usage: "abort the run if it has not completed within `DURATION` ( overrides any built-in default )",
+		},
+		&cli.StringFlag{
+			Name:  "env-file",
+			Usage: "load KEY=VALUE environment variables from `FILE` before resolving flags",
+		},
+		&cli.StringFlag{
+			Name:  "batch",
+			Usage: "run each line of `FILE` ( - for stdin ) as a separate command invocation, sharing initialization",
+		},
+	)
+	app.Flags = append(app.Flags, profileFlags...)
+	app.Flags = append(app.Flags, cpuFlags...)
+	app.Flags = append(app.Flags, printConfigFlags...)
+
 	app.Before = func(cctx *cli.Context) error {
 
 		// when using lp2p the first is non-actionable and
@@ -208,6 +1024,15 @@ func (uf *UFcli) RunAndExit(parentCtx context.Context) {
 		logging.SetLogLevel("net/identify", "ERROR")  //nolint:errcheck
 		logging.SetLogLevel("canonical-log", "ERROR") //nolint:errcheck
 
+		// as early as possible, but after GetLogger() applied its default
+		if cctx.Bool("quiet") {
+			uf.GetLogger()
+			setLogLevel("ERROR")
+		}
+
+		topCctx = cctx
+		explicit = explicitFlags(cctx, app.Flags)
+
 		// pull settings from config file if set
 		if uf.TOMLPath != "" {
 			if err := altsrc.InitInputSourceWithContext(
@@ -218,17 +1043,80 @@ func (uf *UFcli) RunAndExit(parentCtx context.Context) {
 			)(cctx); err != nil {
 				return cmn.WrErr(err)
 			}
+
+			// semantic rather than byte-wise: formatting and comments do not matter
+			if uf.LockPerConfig {
+				var conf map[string]interface{}
+				if _, err := toml.DecodeFile(uf.TOMLPath, &conf); err != nil {
+					return cmn.WrErr(err)
+				}
+				h, err := cmn.HashStable(conf)
+				if err != nil {
+					return cmn.WrErr(err)
+				}
+				configHash = h[:12]
+			}
 		}
 
-		promPushConf.url = cctx.String("prometheus_push_url")
-		promPushConf.user = cctx.String("prometheus_push_user")
-		promPushConf.pass = cctx.String("prometheus_push_pass")
-		promPushConf.instance = cctx.String("prometheus_instance")
+		// merge in dynamic commands ( if any ) before we try to figure out what to dispatch to
+		// NOTE: these will not show up in the top-level --help, as urfave handles it before Before()
+		if !cmn.IsNil(uf.CommandProvider) {
+			provided, err := uf.CommandProvider(cctx)
+			if err != nil {
+				return cmn.WrErr(err)
+			}
+
+			seen := make(map[string]struct{})
+			for _, c := range cctx.App.Commands {
+				for _, n := range c.Names() {
+					seen[n] = struct{}{}
+				}
+			}
+			for _, c := range provided {
+				for _, n := range c.Names() {
+					if _, dup := seen[n]; dup {
+						return cmn.WrErr(fmt.Errorf("provided command '%s' clashes with an already registered command or alias '%s'", c.Name, n))
+					}
+					seen[n] = struct{}{}
+				}
+			}
+
+			// the root command was already assembled by the time we are invoked: update both
+			cctx.App.Commands = append(cctx.App.Commands, decorateCommands(provided)...)
+			cctx.Command.Subcommands = cctx.App.Commands
+		}
+
+		if cctx.IsSet("print-config") {
+			if cctx.Args().Present() {
+				return cmn.WrErr(errors.New("--print-config can not be combined with a command on the command line"))
+			}
+			cctx.Command.Action = func(cctx *cli.Context) error {
+				return uf.printConfig(cctx.App.Writer, cctx, explicit, runArgs)
+			}
+			return nil
+		}
+
+		noMetrics = cctx.Bool("no-metrics")
+		readPushConf(cctx)
+		reportJSON = cctx.Bool("report-json")
+		rs.dryRun = cctx.Bool("dry-run")
+		runTimeout = uf.RunTimeout
+		if cctx.IsSet("timeout") {
+			runTimeout = cctx.Duration("timeout")
+		}
+
+		var err error
+		if maxProcs, err = uf.applyCPULimits(cctx); err != nil {
+			return err
+		}
+		if prof, err = startProfiling(cctx); err != nil {
+			return err
+		}
 
 		// Before() is always called with the *top* cctx in place, not the final one resolved
 		// Figure out what is in os.Args out-of-band
 		{
-			cmdNames := make(map[string]string)
+			cmdNames = make(map[string]string)
 			for _, c := range cctx.App.Commands {
 				if c.Name == "help" || c.Name == "h" {
 					continue
@@ -238,19 +1126,60 @@ func (uf *UFcli) RunAndExit(parentCtx context.Context) {
 					cmdNames[a] = c.Name
 				}
 			}
+			if err := uf.validateCommandAliases(cmdNames); err != nil {
+				return err
+			}
+			if usedAlias != "" {
+				uf.warnCommandAlias(usedAlias)
+			}
+
+			if cctx.Bool("list-metrics") {
+				if cctx.Args().Present() {
+					return cmn.WrErr(errors.New("--list-metrics can not be combined with a command on the command line"))
+				}
+				cmds := []string{"Action"}
+				if len(cmdNames) > 0 {
+					cmds = cmds[:0]
+					for alias, name := range cmdNames {
+						if alias == name {
+							cmds = append(cmds, name)
+						}
+					}
+				}
+				cctx.Command.Action = func(cctx *cli.Context) error {
+					uf.listMetrics(cctx.App.Writer, cmds)
+					return nil
+				}
+				return nil
+			}
+
+			// batch mode: hijack the root action, the lifecycle is driven from there
+			if batchPath := cctx.String("batch"); batchPath != "" {
+				if len(cmdNames) == 0 {
+					return cmn.WrErr(errors.New("--batch requires an app with subcommands"))
+				}
+				if cctx.Args().Present() {
+					return cmn.WrErr(errors.New("--batch can not be combined with a command on the command line"))
+				}
+				if uf.RepeatEvery > 0 {
+					return cmn.WrErr(errors.New("--batch can not be combined with RepeatEvery"))
+				}
+				cctx.Command.Action = func(cctx *cli.Context) error { return runBatch(cctx, batchPath) }
+				return nil
+			}
 
 			// process os.Args even if there are no cmdNames: need to short-circuit --help/-h
-			for i := 1; i < len(os.Args); i++ {
+			for i := 1; i < len(runArgs); i++ {
 
 				// if we are in help context - no locks and no start/stop timers
-				if os.Args[i] == `-h` || os.Args[i] == `--help` {
+				if runArgs[i] == `-h` || runArgs[i] == `--help` {
 					return nil
 				}
 
 				if currentCmd != "" {
 					continue
 				}
-				currentCmd = cmdNames[os.Args[i]]
+				currentCmd = cmdNames[runArgs[i]]
 			}
 
 			// not everything has subcommands
@@ -264,28 +1193,226 @@ func (uf *UFcli) RunAndExit(parentCtx context.Context) {
 			}
 		}
 
-		var err error
-		if !uf.AllowConcurrentRuns {
-			if uf.currentCmdLock, err = fslock.Lock(
-				os.TempDir(),
-				promStr(app.Name)+"-"+promStr(currentCmd), // reuse promstr as path-safe stuff
-			); err != nil {
-				return err // no xerrors wrap on purpose
+		if uf.StartupJitter > 0 {
+			delay := cmn.Jitter(uf.StartupJitter/2, 1)
+			uf.GetLogger().Infof("delaying start of '%s' by %s", currentCmd, cmn.HumanDuration(delay))
+			t := time.NewTimer(delay)
+			select {
+			case <-t.C:
+			case <-cctx.Context.Done():
+				t.Stop()
+				return cmn.WrErr(cctx.Context.Err())
 			}
 		}
 
-		uf.GetLogger().Infow(fmt.Sprintf("=== BEGIN '%s' run", currentCmd))
-		didBegin = true
+		if err := uf.checkClockSkew(cctx.Context); err != nil {
+			return err
+		}
 
-		if uf.GlobalInit != nil {
-			resourcesCloser, err = uf.GlobalInit(cctx, uf)
+		if err := beginRun(commandFlagsContext(cctx, currentCmd, cctx.Args().Tail())); err != nil {
+			return err
 		}
-		return cmn.WrErr(err)
+
+		if !cmn.IsNil(uf.GlobalInit) {
+			if resourcesCloser, err = uf.GlobalInit(cctx, uf); err != nil {
+				return cmn.WrErr(err)
+			}
+		}
+
+		if err := checkShouldRun(cctx); err != nil {
+			return err
+		}
+
+		if !cmn.IsNil(uf.Warmup) {
+			return cmn.WrErr(uf.Warmup(cctx.Context))
+		}
+		return nil
+	}
+
+	if scopeErr = uf.loadEnvFile(runArgs); scopeErr != nil {
+		return
 	}
+	runArgs, usedAlias = uf.rewriteCommandAlias(runArgs, app.Flags)
 
 	// the function ends after this block, scopeErr is examined in the defer above
 	// organized in this bizarre way in order to catch panics
-	scopeErr = (&app).RunContext(ctx, os.Args)
+	scopeErr = (&app).RunContext(ctx, runArgs)
+}
+
+func (uf *UFcli) awaitBackground(rs *runState) {
+	done := make(chan error, 1)
+	go func() { done <- rs.background.Wait() }()
+
+	var timeout <-chan time.Time
+	if uf.CloserTimeout > 0 {
+		t := time.NewTimer(uf.CloserTimeout)
+		defer t.Stop()
+		timeout = t.C
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			uf.GetLogger().Warnf("background task(s) failed: %+v", err)
+		}
+	case <-timeout:
+		uf.GetLogger().Warnf("background tasks did not complete within %s, proceeding without them", cmn.HumanDuration(uf.CloserTimeout))
+	}
+}
+
+func (uf *UFcli) closeResources(closer func() error) {
+	if uf.CloserTimeout <= 0 {
+		if err := cmn.WithRecover(closer)(); err != nil {
+			uf.GetLogger().Warnf("error encountered during after-shutdown cleanup: %+v", err)
+		}
+		return
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmn.WithRecover(closer)() }()
+
+	t := time.NewTimer(uf.CloserTimeout)
+	defer t.Stop()
+	select {
+	case err := <-done:
+		if err != nil {
+			uf.GetLogger().Warnf("error encountered during after-shutdown cleanup: %+v", err)
+		}
+	case <-t.C:
+		uf.GetLogger().Warnf("after-shutdown cleanup did not complete within %s, proceeding without it", cmn.HumanDuration(uf.CloserTimeout))
+	}
+}
+
+type skipRun struct {
+	reason string
+}
+
+func (s *skipRun) Error() string { return "run skipped: " + s.reason }
+
+func (uf *UFcli) stateFilePerm() os.FileMode {
+	if uf.LockFilePerm != 0 {
+		return uf.LockFilePerm
+	}
+	return 0644
+}
+
+func lastSuccessPath(runKey string) string {
+	return filepath.Join(os.TempDir(), runKey+".last-success")
+}
+
+type lastSuccessState struct {
+	Finished time.Time `json:"finished"`
+}
+
+// a missing or unparseable state file is equivalent to "never ran"
+func (uf *UFcli) readLastSuccess(runKey string) (time.Time, bool) {
+	var st lastSuccessState
+	if err := cmn.ReadJSONFile(lastSuccessPath(runKey), &st); err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			uf.GetLogger().Warnf("ignoring unusable last successful run state: %s", err)
+		}
+		return time.Time{}, false
+	}
+	return st.Finished, true
+}
+
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Unwrap() error              { return e.err }
+func (e *exitError) Error() string              { return fmt.Sprint(e) }
+func (e *exitError) Format(s fmt.State, v rune) { xerrors.FormatError(e, s, v) }
+func (e *exitError) FormatError(p xerrors.Printer) error {
+	// transparent: render only the wrapped error, with all its detail
+	if xerr, isXerrFmt := e.err.(xerrors.Formatter); isXerrFmt {
+		_ = xerr.FormatError(p)
+	} else {
+		p.Print(e.err.Error())
+	}
+	return nil
+}
+
+// ExitError wraps err such that, when returned from an action, the process
+// exits with the supplied code instead of the default 1. The wrapped error
+// is logged and the failure metrics are emitted as usual.
+func ExitError(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{code: code, err: err}
+}
+
+func (uf *UFcli) isLockConflict(err error) bool {
+	if !cmn.IsNil(uf.IsLockConflict) {
+		return uf.IsLockConflict(err)
+	}
+	return errors.As(err, new(fslock.LockedError))
+}
+
+func (uf *UFcli) pushOnFailureOnly(cmd string) bool {
+	if v, found := uf.PushOnFailureOnlyBy[cmd]; found {
+		return v
+	}
+	return uf.PushOnFailureOnly
+}
+
+func (uf *UFcli) metricsName() string {
+	if uf.MetricsName != "" {
+		return uf.MetricsName
+	}
+	return uf.AppConfig.Name
+}
+
+// commandFlagsContext returns a context of the named command with its flags
+// parsed from args, for hooks running before urfave/cli parses them itself.
+// The flags are mirrored as plain strings and bools, leaving the command's own
+// flag values untouched: the hook sees values from args, env vars and
+// defaults via cctx.String()/Int()/Bool() and friends, but not values from a
+// config file. For an app without subcommands cctx itself is returned.
+func commandFlagsContext(cctx *cli.Context, cmdName string, args []string) *cli.Context {
+	cmd := cctx.App.Command(cmdName)
+	if cmd == nil {
+		return cctx
+	}
+
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	for _, f := range cmd.Flags {
+		names := f.Names()
+		if df, ok := f.(cli.DocGenerationFlag); ok && !df.TakesValue() {
+			var def bool
+			if bf, ok := f.(*cli.BoolFlag); ok {
+				def = bf.Value
+			}
+			b := new(bool)
+			for _, n := range names {
+				fs.BoolVar(b, n, def, "")
+			}
+		} else {
+			var def string
+			if ok {
+				def = df.GetValue()
+			}
+			s := new(string)
+			for _, n := range names {
+				fs.StringVar(s, n, def, "")
+			}
+		}
+		if ef, ok := f.(interface{ GetEnvVars() []string }); ok && len(names) > 0 {
+			for _, ev := range ef.GetEnvVars() {
+				if v, found := os.LookupEnv(ev); found {
+					fs.Set(names[0], v) //nolint:errcheck
+					break
+				}
+			}
+		}
+	}
+	fs.Parse(args) //nolint:errcheck // reported by urfave/cli once it parses them
+
+	c := cli.NewContext(cctx.App, fs, cctx)
+	c.Command = cmd
+	return c
 }
 
 var globalMutex sync.Mutex
@@ -306,6 +1433,44 @@ func (uf *UFcli) GetLogger() Logger {
 	return uf.Logger
 }
 
+func writePromTextfile(path string, collectors ...prometheus.Collector) error {
+	reg := prometheus.NewRegistry()
+	for _, c := range collectors {
+		if err := reg.Register(c); err != nil {
+			return cmn.WrErr(err)
+		}
+	}
+	mfs, err := reg.Gather()
+	if err != nil {
+		return cmn.WrErr(err)
+	}
+
+	var buf bytes.Buffer
+	for _, mf := range mfs {
+		if _, err := expfmt.MetricFamilyToText(&buf, mf); err != nil {
+			return cmn.WrErr(err)
+		}
+	}
+
+	return cmn.AtomicWriteFile(path, buf.Bytes(), 0644)
+}
+
+func (uf *UFcli) metricStr(s string) string {
+	if !cmn.IsNil(uf.MetricNameSanitizer) {
+		return uf.MetricNameSanitizer(s)
+	}
+	return SanitizeMetricName(s)
+}
+
+// SanitizeMetricName is the default MetricNameSanitizer: every run of one or
+// more non-alphanumeric characters is collapsed into a single `_`. Note that
+// this is lossy: `sync-v2`, `sync.v2` and `sync--v2` all become `sync_v2`.
+// Leading digits are kept as-is, since the result is always embedded after a
+// prefix ( e.g. `myapp_2fa` ) rather than used as a complete metric name.
+func SanitizeMetricName(s string) string {
+	return promStr(s)
+}
+
 var nonAlphanumericRun = regexp.MustCompile(`[^a-zA-Z0-9]+`) //nolint:revive
 func promStr(s string) string {
 	return nonAlphanumericRun.ReplaceAllString(s, "_")
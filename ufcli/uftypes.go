@@ -1,21 +1,79 @@
 package ufcli
 
 import (
+	"flag"
+	"strings"
+
+	"github.com/ribasushi/go-toolbox/cmn"
 	"github.com/urfave/cli/v2"
 	"github.com/urfave/cli/v2/altsrc"
 )
 
 //nolint:revive
 type (
-	App        = cli.App
-	Context    = cli.Context
-	Command    = cli.Command
-	Flag       = cli.Flag
-	BoolFlag   = cli.BoolFlag
-	IntFlag    = cli.IntFlag
-	UintFlag   = cli.UintFlag
-	StringFlag = cli.StringFlag
+	App             = cli.App
+	Context         = cli.Context
+	Command         = cli.Command
+	Flag            = cli.Flag
+	BoolFlag        = cli.BoolFlag
+	IntFlag         = cli.IntFlag
+	UintFlag        = cli.UintFlag
+	StringFlag      = cli.StringFlag
+	StringSliceFlag = cli.StringSliceFlag
 )
 
 //nolint:revive
-var ConfStringFlag = altsrc.NewStringFlag
+var (
+	ConfStringFlag      = altsrc.NewStringFlag
+	ConfStringSliceFlag = altsrc.NewStringSliceFlag
+)
+
+// KeyValueFlag is a repeatable flag accepting `k=v` values, see KVFlag
+type KeyValueFlag struct {
+	*cli.GenericFlag
+}
+
+// KVFlag returns a repeatable flag accepting `k=v` values, e.g. `--label env=prod --label region=us`.
+// Every occurrence ( as well as the content of an environment variable ) is a single pair taken
+// verbatim: unlike with a StringSliceFlag commas are not separators, thus `--label k=a,b` has a
+// value of `a,b`. Use KVFlagValue to retrieve the parsed result.
+func KVFlag(name, usage string) *KeyValueFlag {
+	return &KeyValueFlag{GenericFlag: &cli.GenericFlag{
+		Name:  name,
+		Usage: usage + " (repeatable, in key=value format)",
+		Value: new(kvPairs),
+	}}
+}
+
+// Apply implements cli.Flag
+func (f *KeyValueFlag) Apply(set *flag.FlagSet) error {
+	// flags are applied anew for every --batch line: start from scratch
+	f.GenericFlag.Value = new(kvPairs)
+	return f.GenericFlag.Apply(set)
+}
+
+// the raw, unsplit values of a KeyValueFlag
+type kvPairs []string
+
+func (p *kvPairs) Set(v string) error {
+	*p = append(*p, v)
+	return nil
+}
+
+func (p *kvPairs) String() string {
+	return strings.Join(*p, ", ")
+}
+
+// Get implements flag.Getter, as cli.Context.Value ( e.g. --print-config ) requires
+func (p *kvPairs) Get() interface{} {
+	return append([]string(nil), *p...)
+}
+
+// KVFlagValue parses the values of a KVFlag, erroring on malformed entries and duplicate keys
+func KVFlagValue(cctx *cli.Context, name string) (map[string]string, error) {
+	var pairs []string
+	if p, isKV := cctx.Generic(name).(*kvPairs); isKV {
+		pairs = *p
+	}
+	return cmn.ParseKV(pairs)
+}
@@ -0,0 +1,56 @@
+package ufcli
+
+import (
+	"maps"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+)
+
+func TestKVFlag(t *testing.T) {
+	var got map[string]string
+	var gotErr error
+	app := &cli.App{
+		Flags: []cli.Flag{KVFlag("kv", "pairs")},
+		Action: func(cctx *cli.Context) error {
+			got, gotErr = KVFlagValue(cctx, "kv")
+			raw, _ := cctx.Value("kv").([]string)
+			if len(raw) != len(got) && gotErr == nil {
+				t.Errorf("raw values %q do not match the parsed %q", raw, got)
+			}
+			return nil
+		},
+	}
+
+	for _, tc := range []struct {
+		args []string
+		exp  map[string]string
+	}{
+		{[]string{"--kv", "k=a,b", "--kv", "x=1"}, map[string]string{"k": "a,b", "x": "1"}},
+		{[]string{"--kv", "a=1,b=2"}, map[string]string{"a": "1,b=2"}},
+		{[]string{"--kv", "k= spaced , value"}, map[string]string{"k": " spaced , value"}},
+		{[]string{"--kv=eq=in=value"}, map[string]string{"eq": "in=value"}},
+		// every run starts afresh
+		{[]string{"--kv", "k=other"}, map[string]string{"k": "other"}},
+		{nil, map[string]string{}},
+	} {
+		if err := app.Run(append([]string{"app"}, tc.args...)); err != nil {
+			t.Fatal(err)
+		}
+		if gotErr != nil || !maps.Equal(got, tc.exp) {
+			t.Errorf("%q parsed as %q ( err: %v ), expected %q", tc.args, got, gotErr, tc.exp)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"--kv", "novalue"},
+		{"--kv", "k=1", "--kv", "k=2"},
+	} {
+		if err := app.Run(append([]string{"app"}, args...)); err != nil {
+			t.Fatal(err)
+		}
+		if gotErr == nil {
+			t.Errorf("%q parsed without an error", args)
+		}
+	}
+}